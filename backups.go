@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// backupPath returns the path a build of revision installed at targetPath is kept at.
+func backupPath(targetPath string, revision string) string {
+	return targetPath + "." + revision
+}
+
+// backups returns the revisions kept next to targetPath, the highest revision first.
+func backups(targetPath string) ([]string, error) {
+	matches, err := filepath.Glob(backupPath(targetPath, "*"))
+	if err != nil {
+		return nil, err
+	}
+	var revisions []string
+	for _, m := range matches {
+		revision := strings.TrimPrefix(m, targetPath+".")
+		if _, err := strconv.ParseUint(revision, 10, 64); err == nil {
+			revisions = append(revisions, revision)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		a, _ := strconv.ParseUint(revisions[i], 10, 64)
+		b, _ := strconv.ParseUint(revisions[j], 10, 64)
+		return a > b
+	})
+	return revisions, nil
+}
+
+// keepBackup keeps the original directory at oldPath as a backup of targetPath, named after its revision.
+// Afterwards, only the keep backups with the highest revisions are retained.
+// If the revision of the original directory is unknown, it is deleted instead.
+func keepBackup(oldPath string, targetPath string, keep int) error {
+	m, err := readMetadata(oldPath)
+	if err != nil {
+		return os.RemoveAll(oldPath)
+	}
+	dst := backupPath(targetPath, m.Revision)
+	err = os.RemoveAll(dst)
+	if err != nil {
+		return err
+	}
+	err = rename(oldPath, dst)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "\nKept old directory as \"%s\"\n", dst)
+
+	revisions, err := backups(targetPath)
+	if err != nil {
+		return err
+	}
+	for len(revisions) > keep {
+		pruned := backupPath(targetPath, revisions[len(revisions)-1])
+		err = os.RemoveAll(pruned)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Deleted backup \"%s\"\n", pruned)
+		revisions = revisions[:len(revisions)-1]
+	}
+	return nil
+}
+
+// rollback swaps the build installed at targetPath with the kept backup of the highest revision.
+// The installed build becomes a backup itself, so a rollback can be undone by another one.
+func rollback(targetPath string) (string, error) {
+	current, err := readMetadata(targetPath)
+	if err != nil {
+		return "", fmt.Errorf("revision of the installed build is unknown: %v", err)
+	}
+	revisions, err := backups(targetPath)
+	if err != nil {
+		return "", err
+	}
+	if len(revisions) == 0 {
+		return "", fmt.Errorf("there are no backups of \"%s\"", targetPath)
+	}
+
+	if pathExists(targetPath + oldExt) {
+		return "", fmt.Errorf("the previous build \"%s\" is in the way, delete it with -prune-backups first", targetPath+oldExt)
+	}
+
+	// The backup is restored rather than extracted, so neither it nor the replaced build may be deleted on a failure or an interruption
+	inst := newInstallation(backupPath(targetPath, revisions[0]), targetPath)
+	inst.keepTmp, inst.keepOld = true, true
+	cleanup, _ := handleSignals(inst, interruptExitCode)
+	defer cleanup()
+	_, err = inst.install()
+	if err != nil {
+		return "", err
+	}
+	return revisions[0], rename(targetPath+oldExt, backupPath(targetPath, current.Revision))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// writeRevision creates a build at path, installed as revision.
+func writeRevision(t *testing.T, path string, revision string) {
+	t.Helper()
+	writeBuild(t, path, revision)
+	err := writeMetadata(path, &installMetadata{Revision: revision, Platform: "Linux_x64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "chromium")
+	writeRevision(t, targetPath, "300")
+	writeRevision(t, backupPath(targetPath, "200"), "200")
+	writeRevision(t, backupPath(targetPath, "100"), "100")
+
+	revision, err := rollback(targetPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision != "200" || buildAt(targetPath) != "200" {
+		t.Errorf("rolled back to %s with build %q at the target path, want 200", revision, buildAt(targetPath))
+	}
+	if buildAt(backupPath(targetPath, "300")) != "300" || buildAt(backupPath(targetPath, "100")) != "100" {
+		t.Error("the replaced build or the older backup has not been kept")
+	}
+	assertNotExist(t, backupPath(targetPath, "200"))
+	assertNotExist(t, targetPath+oldExt)
+}
+
+func TestRollbackFailure(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "chromium")
+	writeRevision(t, targetPath, "300")
+	writeRevision(t, backupPath(targetPath, "200"), "200")
+	replaceRename(t, backupPath(targetPath, "200"), errors.New("simulated failure"))
+
+	_, err := rollback(targetPath)
+	if err == nil {
+		t.Fatal("rollback succeeded despite the failing rename")
+	}
+	if buildAt(targetPath) != "300" || buildAt(backupPath(targetPath, "200")) != "200" {
+		t.Errorf("found %q at the target path and %q as backup, want them unchanged", buildAt(targetPath), buildAt(backupPath(targetPath, "200")))
+	}
+	assertNotExist(t, targetPath+oldExt)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/fried-ice/chromiumup/downloadextract"
+)
+
+// parseBisectRange parses a range of build numbers given as "good:bad".
+func parseBisectRange(s string) (good, bad uint64, err error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return 0, 0, errors.New("range must be given as good:bad")
+	}
+	good, err = strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid good build \"%s\"", parts[0])
+	}
+	bad, err = strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid bad build \"%s\"", parts[1])
+	}
+	if good >= bad {
+		return 0, 0, errors.New("the good build must be older than the bad one")
+	}
+	return good, bad, nil
+}
+
+// bisect finds the first bad build between good and bad among the builds available upstream for platform.
+// Each tested build is extracted to a temporary directory, which is passed to testCmd in the CHROMIUMUP_BUILD_DIR environment variable, along with the revision in CHROMIUMUP_REVISION.
+// An exit code of 0 marks the build as good, any other exit code as bad.
+func bisect(client *http.Client, up upstream, platform, file string, good, bad uint64, testCmd string) (string, error) {
+	builds, err := listBuilds(client, up, platform)
+	if err != nil {
+		return "", fmt.Errorf("could not list builds: %v", err)
+	}
+	var candidates []string
+	for _, b := range builds {
+		n, _ := strconv.ParseUint(b, 10, 64)
+		if n > good && n < bad {
+			candidates = append(candidates, b)
+		}
+	}
+
+	// candidates[lo] is known good, candidates[hi] known bad, with the given bounds just outside
+	lo, hi := -1, len(candidates)
+	firstBad := strconv.FormatUint(bad, 10)
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		revision := candidates[mid]
+		fmt.Fprintf(stdout, "Testing build %s, %v builds left to test\n", revision, hi-lo-1)
+		isGood, err := testBuild(client, up, platform, file, revision, testCmd)
+		if err != nil {
+			return "", fmt.Errorf("build %s: %v", revision, err)
+		}
+		if isGood {
+			fmt.Fprintf(stdout, "Build %s is good\n", revision)
+			lo = mid
+		} else {
+			fmt.Fprintf(stdout, "Build %s is bad\n", revision)
+			hi = mid
+			firstBad = revision
+		}
+	}
+	return firstBad, nil
+}
+
+// testBuild extracts build revision to a temporary directory and reports whether testCmd succeeds for it.
+func testBuild(client *http.Client, up upstream, platform, file, revision, testCmd string) (bool, error) {
+	dir, err := ioutil.TempDir("", "chromiumup-bisect-")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(dir)
+
+	archiveURL, err := up.downloadURL(platform, revision, file)
+	if err != nil {
+		return false, err
+	}
+	dE := downloadextract.NewDownloadExtractor(archiveURL, dir)
+	dE.SetHTTPClient(client)
+	dE.SetLogger(log.New(stdout, "", 0))
+	if md5Sum, err := up.snapshots(client, 0).ArchiveMD5(context.Background(), up.apiBase, platform, revision, file); err == nil {
+		dE.SetExpectedChecksum("md5", md5Sum)
+	}
+	dE.OmitTopDirs(1)
+	_, err = dE.Run()
+	if err != nil {
+		return false, err
+	}
+
+	err = shellCommand(testCmd, "CHROMIUMUP_BUILD_DIR="+dir, "CHROMIUMUP_REVISION="+revision).Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not run test command: %v", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// latestCacheFile is the name of the file below the user cache directory which caches resolved latest builds.
+const latestCacheFile = "latest.json"
+
+// cachedRevision is a latest build number resolved at Fetched.
+type cachedRevision struct {
+	Revision string    `json:"revision"`
+	Fetched  time.Time `json:"fetched"`
+}
+
+// latestCachePath returns the path of the cache of latest builds.
+func latestCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "chromiumup", latestCacheFile), nil
+}
+
+// cachedLatestBuild is like latestBuild, but returns a build number resolved less than ttl ago from the on-disk cache.
+// Entries are keyed by the URL of the LAST_CHANGE object, so different platforms and mirrors do not mix.
+// The cache is a mere optimization, so failing to read or write it is not an error. A ttl of 0 disables the cache.
+func cachedLatestBuild(client *http.Client, up upstream, platform string, retries int, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		return latestBuild(client, up, platform, retries)
+	}
+
+	key, err := up.snapshots(client, 0).LatestURL(up.base, platform)
+	if err != nil {
+		return "", err
+	}
+	cache := map[string]cachedRevision{}
+	path, err := latestCachePath()
+	if err == nil {
+		if b, err := ioutil.ReadFile(path); err == nil {
+			json.Unmarshal(b, &cache)
+		}
+	}
+	if c, ok := cache[key]; ok && c.Revision != "" && time.Since(c.Fetched) < ttl {
+		return c.Revision, nil
+	}
+
+	revision, err := latestBuild(client, up, platform, retries)
+	if err != nil {
+		return "", err
+	}
+	if path != "" {
+		cache[key] = cachedRevision{Revision: revision, Fetched: time.Now().UTC()}
+		writeCache(path, cache)
+	}
+	return revision, nil
+}
+
+// writeCache stores cache at path, replacing the file atomically so concurrent invocations never read a partial one.
+func writeCache(path string, cache map[string]cachedRevision) error {
+	b, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), latestCacheFile+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(append(b, '\n'))
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
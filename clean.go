@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cleanPaths returns the existing paths -clean removes for the installation at targetPath: the installation itself, the previous builds kept by -keep-backup and -keep, and the leftovers of interrupted runs at tmpPath.
+// An error is returned if targetPath exists but contains no build installed by chromiumup, so an unrelated directory is never removed.
+func cleanPaths(targetPath string, tmpPath string) ([]string, error) {
+	var paths []string
+	if pathExists(targetPath) {
+		_, err := readMetadata(targetPath)
+		if err != nil {
+			_, err = readMetadata(filepath.Join(targetPath, currentLink))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("\"%s\" contains no build installed by chromiumup", targetPath)
+		}
+		paths = append(paths, targetPath)
+	}
+
+	revisions, err := backups(targetPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, revision := range revisions {
+		paths = append(paths, backupPath(targetPath, revision))
+	}
+	// The partial archive of -resume is stored next to the temporary directory
+	for _, path := range []string{targetPath + oldExt, tmpPath, tmpPath + ".part", tmpPath + ".part.offset"} {
+		if pathExists(path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// confirm asks the user on the terminal whether to proceed with question and reports whether the answer was yes.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// configFile is the name of the config file which is read from the working directory, unless -config is given.
+const configFile = ".chromiumuprc"
+
+// targetKey is the config key of the target path, which is no flag but the positional argument.
+const targetKey = "target"
+
+// readConfig reads the config file at path.
+// Every line holds a value like "name = value", where name is the name of a flag without the leading dash, or "target" for the target path.
+// Empty lines and lines starting with # are ignored.
+func readConfig(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("line %v: expected \"name = value\"", lineNumber)
+		}
+		values[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return values, scanner.Err()
+}
+
+// applyConfig sets the flags to the values of the config, unless they were given on the command line, which takes precedence.
+// The target path of the config is returned, as it is no flag.
+func applyConfig(values map[string]string) (target string, err error) {
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	for name, value := range values {
+		if name == targetKey {
+			target = value
+			continue
+		}
+		if name == "config" {
+			return "", errors.New("a config file cannot refer to another one")
+		}
+		if flag.Lookup(name) == nil {
+			return "", fmt.Errorf("unknown setting \"%s\"", name)
+		}
+		if set[name] {
+			continue
+		}
+		err = flag.Set(name, value)
+		if err != nil {
+			return "", fmt.Errorf("invalid value \"%s\" for \"%s\": %v", value, name, err)
+		}
+	}
+	return target, nil
+}
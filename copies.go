@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// installCopies installs copies of the build revision of platform at sourcePath to every path of targets, so several target paths are served by a single download.
+// Targets which already have the build installed are left untouched, unless force is set.
+// Every copy is staged next to its target and moved in place like a downloaded build, so a failed copy keeps the existing installation.
+// The installation of every copy is passed to track before it begins, so it is cleaned up on interruptions, see handleSignals.
+func installCopies(sourcePath string, targets []string, revision string, platform string, force bool, track func(*installation)) error {
+	for _, target := range targets {
+		if m, err := readMetadata(target); err == nil && !force && m.Revision == revision && m.Platform == platform {
+			fmt.Fprintf(stdout, "Build %s is already installed at \"%s\", already up to date\n", revision, target)
+			continue
+		}
+
+		tmpPath := target + tmpExt
+		err := os.RemoveAll(tmpPath)
+		if err != nil {
+			return fmt.Errorf("could not delete \"%s\" left behind by an earlier run: %v", tmpPath, err)
+		}
+		inst := newInstallation(tmpPath, target)
+		track(inst)
+		err = copyDir(sourcePath, tmpPath)
+		if err != nil {
+			os.RemoveAll(tmpPath)
+			return fmt.Errorf("could not copy build to \"%s\": %v", tmpPath, err)
+		}
+		pathExisted, err := inst.install()
+		if err != nil {
+			return fmt.Errorf("could not move build to \"%s\": %v", target, err)
+		}
+		if pathExisted {
+			os.RemoveAll(target + oldExt)
+		}
+		fmt.Fprintf(stdout, "Copied build %s to \"%s\"\n", revision, target)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fried-ice/chromiumup/downloadextract"
+)
+
+// configureDial makes transport connect only via IPv4 or IPv6 if ipVersion is "4" or "6" and resolve host names with the DNS server at resolverAddr, if it is not empty.
+// An empty ipVersion keeps dialing both, as usual.
+func configureDial(transport *http.Transport, ipVersion string, resolverAddr string) error {
+	network := "tcp"
+	switch ipVersion {
+	case "":
+	case "4", "6":
+		network += ipVersion
+	default:
+		return fmt.Errorf("invalid IP version \"%s\", allowed values are 4 and 6", ipVersion)
+	}
+
+	dialer := &net.Dialer{Timeout: downloadextract.DialTimeout, KeepAlive: 30 * time.Second}
+	if resolverAddr != "" {
+		if _, _, err := net.SplitHostPort(resolverAddr); err != nil {
+			resolverAddr = net.JoinHostPort(strings.Trim(resolverAddr, "[]"), "53")
+		}
+		dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				d := net.Dialer{Timeout: downloadextract.DialTimeout}
+				return d.DialContext(ctx, network, resolverAddr)
+			},
+		}
+	}
+	transport.DialContext = func(ctx context.Context, _, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address)
+	}
+	return nil
+}
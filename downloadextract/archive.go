@@ -0,0 +1,212 @@
+package downloadextract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/krolaw/zipstream"
+)
+
+// Format is the type of archive a DownloadExtractor extracts.
+type Format int
+
+const (
+	// Auto detects the format from the first bytes of the archive.
+	Auto Format = iota
+	// Zip is a zip archive.
+	Zip
+	// TarGz is a gzip compressed tar archive.
+	TarGz
+)
+
+var (
+	zipMagic = []byte("PK\x03\x04")
+	// emptyZipMagic starts a zip archive without entries, which consists of the end of central directory record only.
+	emptyZipMagic = []byte("PK\x05\x06")
+	gzipMagic     = []byte("\x1f\x8b")
+)
+
+// WithArchiveFormat is the Option equivalent of SetArchiveFormat.
+func WithArchiveFormat(f Format) Option {
+	return func(d *DownloadExtractor) {
+		d.format = f
+	}
+}
+
+// SetArchiveFormat sets the format of the archive.
+// By default, the format is detected automatically.
+func (d *DownloadExtractor) SetArchiveFormat(f Format) {
+	WithArchiveFormat(f)(d)
+}
+
+// entry is a single file or directory of an archive.
+type entry struct {
+	name string
+	info os.FileInfo
+	// linkname is the target of a symbolic link, if the archive format stores it in the header.
+	linkname string
+	// sizeKnown is false if the size of info is not known before the content is read, as for zip entries with a data descriptor.
+	sizeKnown bool
+}
+
+// archiveReader iterates over the entries of an archive.
+// Reading from it returns the content of the current entry.
+type archiveReader interface {
+	io.Reader
+	// Next advances to the next entry and returns io.EOF if there are no more entries.
+	Next() (*entry, error)
+}
+
+// metadataTrailer is implemented by archiveReaders which learn the complete metadata of their entries only after all of them have been read.
+type metadataTrailer interface {
+	// trailer returns all entries of the archive with their complete metadata.
+	trailer() ([]*entry, error)
+}
+
+// newArchiveReader creates an archiveReader for the archive read from r.
+// If f is Auto, the format is detected by peeking at the first bytes.
+func newArchiveReader(r io.Reader, f Format) (archiveReader, error) {
+	if f == Auto {
+		bR := bufio.NewReader(r)
+		magic, err := bR.Peek(len(zipMagic))
+		if err != nil && !(err == io.EOF && len(magic) > 0) {
+			return nil, err
+		}
+		switch {
+		case bytes.HasPrefix(magic, zipMagic), bytes.HasPrefix(magic, emptyZipMagic):
+			f = Zip
+		case bytes.HasPrefix(magic, gzipMagic):
+			f = TarGz
+		default:
+			return nil, errors.New("unknown archive format")
+		}
+		r = bR
+	}
+
+	switch f {
+	case Zip:
+		tail := newTailRecorder(r, maxCentralDirectorySize)
+		return &zipArchive{Reader: zipstream.NewReader(tail), tail: tail}, nil
+	case TarGz:
+		gR, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return &tarArchive{tar.NewReader(gR)}, nil
+	default:
+		return nil, errors.New("unknown archive format")
+	}
+}
+
+// maxCentralDirectorySize is the size up to which the central directory of a zip archive is retained.
+const maxCentralDirectorySize = 4 * 1024 * 1024
+
+// zipArchive streams a zip archive.
+// The local file headers in front of the entries do not contain file modes, those are only stored in the central directory at the end of the archive.
+// Therefore, the last bytes of the stream are retained to read the central directory from.
+type zipArchive struct {
+	*zipstream.Reader
+	tail *tailRecorder
+}
+
+// zipDataDescriptor is the flag of zip entries whose sizes follow their content instead of being stored in the local header.
+const zipDataDescriptor = 0x8
+
+func (z *zipArchive) Next() (e *entry, err error) {
+	defer recoverZipstream(&err)
+	fHdr, err := z.Reader.Next()
+	if err != nil {
+		return nil, err
+	}
+	return &entry{name: fHdr.Name, info: fHdr.FileInfo(), sizeKnown: fHdr.Flags&zipDataDescriptor == 0}, nil
+}
+
+func (z *zipArchive) Read(b []byte) (n int, err error) {
+	defer recoverZipstream(&err)
+	return z.Reader.Read(b)
+}
+
+// recoverZipstream turns a panic of zipstream into an error stored in err.
+// zipstream panics instead of returning an error if the stream breaks off in the middle of an entry with a data descriptor.
+func recoverZipstream(err *error) {
+	if p := recover(); p != nil {
+		*err = fmt.Errorf("%w: zip stream reader failed: %v", io.ErrUnexpectedEOF, p)
+	}
+}
+
+func (z *zipArchive) trailer() ([]*entry, error) {
+	zR, err := zip.NewReader(z.tail, z.tail.total)
+	if err != nil && err != zip.ErrInsecurePath {
+		return nil, err
+	}
+	entries := make([]*entry, 0, len(zR.File))
+	for _, f := range zR.File {
+		entries = append(entries, &entry{name: f.Name, info: f.FileInfo(), sizeKnown: true})
+	}
+	return entries, nil
+}
+
+type tarArchive struct {
+	*tar.Reader
+}
+
+// Next skips all entries but regular files, directories and symbolic links.
+func (t *tarArchive) Next() (*entry, error) {
+	for {
+		tHdr, err := t.Reader.Next()
+		if err != nil {
+			return nil, err
+		}
+		switch tHdr.Typeflag {
+		case tar.TypeReg, tar.TypeDir, tar.TypeSymlink:
+			return &entry{name: strings.TrimPrefix(tHdr.Name, "./"), info: tHdr.FileInfo(), linkname: tHdr.Linkname, sizeKnown: true}, nil
+		}
+	}
+}
+
+// tailRecorder passes reads through to r and retains the last bytes which were read.
+type tailRecorder struct {
+	r     io.Reader
+	max   int
+	buf   []byte
+	total int64
+}
+
+// newTailRecorder creates a tailRecorder retaining at least the last max bytes read from r.
+func newTailRecorder(r io.Reader, max int) *tailRecorder {
+	return &tailRecorder{r: r, max: max}
+}
+
+func (t *tailRecorder) Read(b []byte) (int, error) {
+	n, err := t.r.Read(b)
+	t.total += int64(n)
+	t.buf = append(t.buf, b[:n]...)
+	if len(t.buf) > 2*t.max {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.max:]...)
+	}
+	return n, err
+}
+
+// ReadAt reads from the retained bytes, with off being relative to the start of the stream.
+func (t *tailRecorder) ReadAt(b []byte, off int64) (int, error) {
+	start := t.total - int64(len(t.buf))
+	if off < start {
+		return 0, errors.New("offset is no longer retained")
+	}
+	if off >= t.total {
+		return 0, io.EOF
+	}
+	n := copy(b, t.buf[off-start:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
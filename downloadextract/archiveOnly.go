@@ -0,0 +1,68 @@
+package downloadextract
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WithArchiveOnly is the Option equivalent of SetArchiveOnly.
+func WithArchiveOnly(b bool) Option {
+	return func(d *DownloadExtractor) {
+		d.archiveOnly = b
+	}
+}
+
+// SetArchiveOnly enables, when set to true, saving the downloaded archive as a single file at the output path instead of extracting it.
+// Progress reporting, checksum verification, retries and resuming work as usual, parallel connections are not used in this mode.
+func (d *DownloadExtractor) SetArchiveOnly(b bool) {
+	WithArchiveOnly(b)(d)
+}
+
+// save writes the archive read from r to a file at the output path.
+func (d *DownloadExtractor) save(r io.Reader, result *Result) error {
+	err := os.MkdirAll(filepath.Dir(d.outPath), d.dirMode)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(d.outPath)
+	if err != nil {
+		return err
+	}
+	n, err := d.copyBuffer(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	d.saved(n, result)
+	return nil
+}
+
+// savePartial moves the completely downloaded partial archive of resumable mode to the output path.
+func (d *DownloadExtractor) savePartial(result *Result) error {
+	err := os.MkdirAll(filepath.Dir(d.outPath), d.dirMode)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(d.partialPath(), d.outPath)
+	if err != nil {
+		return err
+	}
+	fInfo, err := os.Stat(d.outPath)
+	if err != nil {
+		return err
+	}
+	d.saved(fInfo.Size(), result)
+	return nil
+}
+
+// saved records an archive of size bytes saved to the output path in result.
+func (d *DownloadExtractor) saved(size int64, result *Result) {
+	result.FilesWritten = 1
+	result.TotalBytes = size
+	d.logger.Printf("Saved archive with a total of %v bytes to \"%s\"\n", size, d.outPath)
+}
@@ -0,0 +1,36 @@
+package downloadextract
+
+import "io"
+
+// defaultBufferSize is the size of the buffers used to copy downloaded data by default.
+const defaultBufferSize = 256 * 1024
+
+// WithBufferSize is the Option equivalent of SetBufferSize.
+func WithBufferSize(n int) Option {
+	return func(d *DownloadExtractor) {
+		if n <= 0 {
+			n = defaultBufferSize
+		}
+		d.bufferSize = n
+	}
+}
+
+// SetBufferSize sets the size of the buffers in bytes, which downloaded data and extracted files are copied with.
+// Larger buffers mean fewer reads and writes, which can raise the throughput of fast connections.
+// The default is 256 KiB, values less than 1 restore it.
+func (d *DownloadExtractor) SetBufferSize(n int) {
+	WithBufferSize(n)(d)
+}
+
+// copyBuffer is like io.Copy, but copies with a buffer of the configured size.
+// Buffers are reused across calls, which may happen concurrently.
+// dst and src are wrapped, as io.CopyBuffer ignores the buffer if dst is an io.ReaderFrom like *os.File or src an io.WriterTo.
+func (d *DownloadExtractor) copyBuffer(dst io.Writer, src io.Reader) (int64, error) {
+	buf, _ := d.buffers.Get().(*[]byte)
+	if buf == nil || len(*buf) != d.bufferSize {
+		b := make([]byte, d.bufferSize)
+		buf = &b
+	}
+	defer d.buffers.Put(buf)
+	return io.CopyBuffer(struct{ io.Writer }{dst}, struct{ io.Reader }{src}, *buf)
+}
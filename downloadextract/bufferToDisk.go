@@ -0,0 +1,68 @@
+package downloadextract
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WithBufferToDisk is the Option equivalent of SetBufferToDisk.
+func WithBufferToDisk(b bool) Option {
+	return func(d *DownloadExtractor) {
+		d.bufferToDisk = b
+	}
+}
+
+// SetBufferToDisk enables, when set to true, downloading the archive completely to a temporary file before extracting it from there, see SetTempFile.
+// Nothing is extracted unless the download is complete and passed the checksum verification, at the cost of writing the archive to disk once more and of the extraction no longer overlapping with the download.
+// The temporary file is deleted afterwards, regardless of the outcome. Resumable mode and parallel connections buffer the archive anyway and take precedence.
+func (d *DownloadExtractor) SetBufferToDisk(b bool) {
+	WithBufferToDisk(b)(d)
+}
+
+// WithTempFile is the Option equivalent of SetTempFile.
+func WithTempFile(path string) Option {
+	return func(d *DownloadExtractor) {
+		d.tempFile = path
+	}
+}
+
+// SetTempFile sets the path of the file the archive is downloaded to by SetBufferToDisk, SetResumable and SetParallelConnections.
+// It defaults to the output path with the suffix ".part". The sidecar file of resumable mode is stored next to it.
+// With SetArchiveOnly, the file is renamed to the output path, so both should be located on the same filesystem.
+func (d *DownloadExtractor) SetTempFile(path string) {
+	WithTempFile(path)(d)
+}
+
+// runBuffered downloads the archive to the temporary file and extracts it afterwards.
+func (d *DownloadExtractor) runBuffered(ctx context.Context, result *Result) error {
+	err := os.MkdirAll(filepath.Dir(d.partialPath()), d.dirMode)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(d.partialPath(), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(d.partialPath())
+	defer f.Close()
+
+	// The checksum is verified by the download, whose error is returned before anything is extracted
+	start := time.Now()
+	err = d.stream(ctx, result, func(archive io.Reader) error {
+		_, err := d.copyBuffer(f, archive)
+		return err
+	})
+	result.NetworkWait = time.Since(start)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	return d.extract(ctx, f, result)
+}
@@ -0,0 +1,29 @@
+package downloadextract
+
+import (
+	"archive/zip"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func BenchmarkRunBufferSize(b *testing.B) {
+	var files []testFile
+	for i := 0; i < 16; i++ {
+		files = append(files, testFile{name: "chrome-linux/file" + strconv.Itoa(i), body: randomBody(2*1024*1024 + i)})
+	}
+	archive := buildZip(b, zip.Store, files...)
+	url := serveArchive(b, archive)
+
+	for _, size := range []int{32 * 1024, defaultBufferSize, 1024 * 1024} {
+		b.Run(strconv.Itoa(size/1024)+"KiB", func(b *testing.B) {
+			b.SetBytes(int64(len(archive)))
+			for i := 0; i < b.N; i++ {
+				_, err := newTestExtractor(url, filepath.Join(b.TempDir(), "out"), WithBufferSize(size)).Run()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
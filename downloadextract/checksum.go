@@ -0,0 +1,101 @@
+package downloadextract
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ErrChecksumMismatch is wrapped by the error returned if the digest of the archive differs from the one of SetExpectedChecksum.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// WithExpectedChecksum is the Option equivalent of SetExpectedChecksum.
+func WithExpectedChecksum(algo string, hexDigest string) Option {
+	return func(d *DownloadExtractor) {
+		d.checksumAlgo = strings.ToLower(algo)
+		d.checksum = strings.ToLower(hexDigest)
+	}
+}
+
+// SetExpectedChecksum enables verification of the downloaded archive.
+// algo is one of "md5", "sha1" or "sha256" and hexDigest the expected hex encoded digest of the raw archive.
+// The run fails if the computed digest does not match.
+func (d *DownloadExtractor) SetExpectedChecksum(algo string, hexDigest string) {
+	WithExpectedChecksum(algo, hexDigest)(d)
+}
+
+// newHash creates a hash.Hash for the checksum algorithm algo.
+func newHash(algo string) (hash.Hash, error) {
+	switch algo {
+	case "md5":
+		return md5.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm \"%s\"", algo)
+	}
+}
+
+// verifyHash compares the digest of h to the expected checksum and logs the result of a match.
+func (d *DownloadExtractor) verifyHash(h hash.Hash) error {
+	sum := hex.EncodeToString(h.Sum(nil))
+	if sum != d.checksum {
+		return fmt.Errorf("%s %w: expected %s, got %s", d.checksumAlgo, ErrChecksumMismatch, d.checksum, sum)
+	}
+	d.logger.Printf("Verified %s checksum %s of archive\n", d.checksumAlgo, sum)
+	return nil
+}
+
+// verifyFile computes the digest of the file at path and compares it to the expected checksum.
+func (d *DownloadExtractor) verifyFile(path string) error {
+	h, err := newHash(d.checksumAlgo)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	return d.verifyHash(h)
+}
+
+// parseGoogHash returns the hex encoded md5 and crc32c digests of the X-Goog-Hash header of Google Cloud Storage responses.
+// The header holds comma separated base64 encoded digests like "crc32c=n03x6A==,md5=Ojk9c3dhfxgoKVVHYwFbHQ==" and may occur multiple times.
+// Missing or malformed digests are returned as empty strings.
+func parseGoogHash(header http.Header) (md5Sum string, crc32cSum string) {
+	for _, value := range header.Values("X-Goog-Hash") {
+		for _, part := range strings.Split(value, ",") {
+			kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			sum, err := base64.StdEncoding.DecodeString(kv[1])
+			if err != nil {
+				continue
+			}
+			switch strings.ToLower(kv[0]) {
+			case "md5":
+				md5Sum = hex.EncodeToString(sum)
+			case "crc32c":
+				crc32cSum = hex.EncodeToString(sum)
+			}
+		}
+	}
+	return md5Sum, crc32cSum
+}
@@ -0,0 +1,64 @@
+package downloadextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunGoogHash(t *testing.T) {
+	archive := buildZip(t, zip.Deflate, testFile{name: "chrome-linux/chrome", body: "binary"})
+	md5Sum := md5.Sum(archive)
+	crc32cSum := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc32cSum, crc32.Checksum(archive, crc32.MakeTable(crc32.Castagnoli)))
+	md5Value := "md5=" + base64.StdEncoding.EncodeToString(md5Sum[:])
+	crc32cValue := "crc32c=" + base64.StdEncoding.EncodeToString(crc32cSum)
+
+	headers := map[string][]string{
+		"single header":   {crc32cValue + "," + md5Value},
+		"several headers": {crc32cValue, md5Value},
+	}
+	for name, values := range headers {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header()["X-Goog-Hash"] = values
+				http.ServeContent(w, r, "", testTime, bytes.NewReader(archive))
+			}))
+			defer srv.Close()
+
+			result, err := newTestExtractor(srv.URL, filepath.Join(t.TempDir(), "out")).Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.ContentLength != int64(len(archive)) {
+				t.Errorf("ContentLength = %v, want %v", result.ContentLength, len(archive))
+			}
+			if want := hex.EncodeToString(md5Sum[:]); result.ServerMD5 != want {
+				t.Errorf("ServerMD5 = %s, want %s", result.ServerMD5, want)
+			}
+			if want := hex.EncodeToString(crc32cSum); result.ServerCRC32C != want {
+				t.Errorf("ServerCRC32C = %s, want %s", result.ServerCRC32C, want)
+			}
+		})
+	}
+}
+
+func TestRunChecksumMismatch(t *testing.T) {
+	archive := buildZip(t, zip.Deflate, testFile{name: "chrome-linux/chrome", body: "binary"})
+	outPath := filepath.Join(t.TempDir(), "out")
+	_, err := newTestExtractor(serveArchive(t, archive), outPath, WithExpectedChecksum("sha256", strings.Repeat("00", 32)), WithRemoveOnFail(true)).Run()
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Run returned %v, want ErrChecksumMismatch", err)
+	}
+	assertNotExist(t, outPath)
+}
@@ -0,0 +1,43 @@
+package downloadextract
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrNotModified is returned if the archive has not changed since the run whose validators were passed to SetIfModified.
+var ErrNotModified = errors.New("archive has not been modified")
+
+// WithIfModified is the Option equivalent of SetIfModified.
+func WithIfModified(etag string, lastModified string) Option {
+	return func(d *DownloadExtractor) {
+		d.ifNoneMatch = etag
+		d.ifModifiedSince = lastModified
+	}
+}
+
+// SetIfModified makes the download conditional on the archive having changed since an earlier run, which returned etag and lastModified in its Result.
+// Requests carry If-None-Match and If-Modified-Since headers for the values which are not empty.
+// If the server responds with 304 Not Modified, the run fails with ErrNotModified before anything is written.
+// A partially downloaded archive of SetResumable is always resumed unconditionally.
+func (d *DownloadExtractor) SetIfModified(etag string, lastModified string) {
+	WithIfModified(etag, lastModified)(d)
+}
+
+// conditionalHeader returns the header which makes a request for the complete archive conditional according to SetIfModified.
+func (d *DownloadExtractor) conditionalHeader() http.Header {
+	header := http.Header{}
+	if d.ifNoneMatch != "" {
+		header.Set("If-None-Match", d.ifNoneMatch)
+	}
+	if d.ifModifiedSince != "" {
+		header.Set("If-Modified-Since", d.ifModifiedSince)
+	}
+	return header
+}
+
+// setValidators stores the validators of resp in result, which identify the version of the archive for SetIfModified.
+func setValidators(resp *http.Response, result *Result) {
+	result.ETag = resp.Header.Get("ETag")
+	result.LastModified = resp.Header.Get("Last-Modified")
+}
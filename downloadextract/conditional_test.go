@@ -0,0 +1,54 @@
+package downloadextract
+
+import (
+	"archive/zip"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunIfModified(t *testing.T) {
+	url := serveArchive(t, buildZip(t, zip.Deflate, testFile{name: "chrome-linux/chrome", body: "binary"}))
+	lastModified := testTime.Format(http.TimeFormat)
+
+	modes := map[string][]Option{
+		"streamed":  nil,
+		"parallel":  {WithParallelConnections(2)},
+		"resumable": {WithResumable(true)},
+	}
+	tests := []struct {
+		name         string
+		etag         string
+		lastModified string
+		notModified  bool
+	}{
+		{name: "no validators"},
+		{name: "other etag", etag: `"v0"`},
+		{name: "etag", etag: testETag, notModified: true},
+		{name: "last modified", lastModified: lastModified, notModified: true},
+	}
+	for mode, opts := range modes {
+		for _, test := range tests {
+			t.Run(mode+"/"+test.name, func(t *testing.T) {
+				outPath := filepath.Join(t.TempDir(), "out")
+				d := newTestExtractor(url, outPath, append(opts, WithIfModified(test.etag, test.lastModified))...)
+				result, err := d.Run()
+				if test.notModified {
+					if !errors.Is(err, ErrNotModified) {
+						t.Errorf("Run returned %v, want ErrNotModified", err)
+					}
+					assertNotExist(t, outPath)
+					assertNotExist(t, d.partialPath())
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.ETag != testETag || result.LastModified != lastModified {
+					t.Errorf("validators are %s and %s, want %s and %s", result.ETag, result.LastModified, testETag, lastModified)
+				}
+			})
+		}
+	}
+}
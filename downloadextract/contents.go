@@ -0,0 +1,114 @@
+package downloadextract
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// ArchiveEntry describes an entry of an archive, as listed by ListContents.
+type ArchiveEntry struct {
+	// Name is the path of the entry within the archive.
+	Name string
+	// Path is the slash separated path the entry would be extracted to, relative to the output path.
+	// It is empty if the entry would not be extracted, as it is located within the omitted top folders or filtered out.
+	Path string
+	// Size is the size of the content of the entry in bytes.
+	Size int64
+	// Mode holds the type and permission bits of the entry.
+	Mode os.FileMode
+}
+
+// ListContents downloads the archive and returns its entries in the order of the archive, without writing anything.
+// Settings affecting which entries are extracted where, like OmitTopDirs, SetInclude and SetFlatten, are reflected in the Path of the entries.
+// The archive is always streamed, settings for resumable and parallel downloads are ignored.
+func (d *DownloadExtractor) ListContents() ([]ArchiveEntry, error) {
+	return d.ListContentsContext(context.Background())
+}
+
+// ListContentsContext is like ListContents, but aborts as soon as ctx is done.
+func (d *DownloadExtractor) ListContentsContext(ctx context.Context) ([]ArchiveEntry, error) {
+	if !atomic.CompareAndSwapInt32(&d.running, 0, 1) {
+		return nil, ErrConcurrentRun
+	}
+	defer atomic.StoreInt32(&d.running, 0)
+	d.listing = true
+	defer func() { d.listing = false }()
+
+	var contents []ArchiveEntry
+	result := Result{ContentLength: -1}
+	err := d.stream(ctx, &result, func(archive io.Reader) error {
+		var err error
+		contents, err = d.listEntries(ctx, archive)
+		return err
+	})
+	return contents, err
+}
+
+// listEntries reads all entries of the archive read from r.
+func (d *DownloadExtractor) listEntries(ctx context.Context, r io.Reader) ([]ArchiveEntry, error) {
+	err := d.checkPatterns()
+	if err != nil {
+		return nil, err
+	}
+	aR, err := newArchiveReader(r, d.format)
+	if err != nil {
+		return nil, formatError(err)
+	}
+	d.flatPaths, d.flatUsed = map[string]string{}, map[string]bool{}
+
+	var contents []ArchiveEntry
+	fHdr, err := aR.Next()
+	for ; err != io.EOF; fHdr, err = aR.Next() {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if err != nil {
+			return nil, formatError(err)
+		}
+		// Sizes of zip entries with data descriptors are only known after their content
+		size, err := io.Copy(ioutil.Discard, formatReader{aR})
+		if err != nil {
+			return nil, fmt.Errorf("archive entry \"%s\": %w", fHdr.name, err)
+		}
+		if fHdr.info.IsDir() {
+			size = 0
+		}
+		rel, err := d.listedPath(fHdr)
+		if err != nil {
+			return nil, fmt.Errorf("archive entry \"%s\": %w", fHdr.name, err)
+		}
+		contents = append(contents, ArchiveEntry{Name: fHdr.name, Path: rel, Size: size, Mode: fHdr.info.Mode()})
+	}
+
+	// Zip archives only reveal file modes in the central directory at their very end
+	if t, ok := aR.(metadataTrailer); ok {
+		entries, err := t.trailer()
+		if err == nil && len(entries) == len(contents) {
+			for i, fHdr := range entries {
+				contents[i].Mode = fHdr.info.Mode()
+			}
+		}
+	}
+	return contents, nil
+}
+
+// listedPath returns the slash separated path the entry fHdr would be extracted to relative to the output path, or an empty string if it would not be extracted.
+func (d *DownloadExtractor) listedPath(fHdr *entry) (string, error) {
+	if d.shortenPath(fHdr.name) == "" || !d.included(fHdr.name) || (fHdr.info.IsDir() && d.flatten) {
+		return "", nil
+	}
+	fPath, err := d.outputPath(fHdr.name)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(d.outPath, fPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
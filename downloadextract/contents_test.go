@@ -0,0 +1,56 @@
+package downloadextract
+
+import (
+	"archive/zip"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestListContents(t *testing.T) {
+	archive := buildZip(t, zip.Deflate,
+		testFile{name: "chrome-linux/"},
+		testFile{name: "chrome-linux/chrome", body: "binary", mode: 0755},
+	)
+	outPath := filepath.Join(t.TempDir(), "out")
+	contents, err := newTestExtractor(serveArchive(t, archive), outPath, WithOmitTopDirs(1)).ListContents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, e := range contents {
+		paths = append(paths, e.Name+" -> "+e.Path)
+	}
+	if want := []string{"chrome-linux/ -> ", "chrome-linux/chrome -> chrome"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("listed %v, want %v", paths, want)
+	}
+	assertNotExist(t, outPath)
+}
+
+func TestListContentsDiskSpace(t *testing.T) {
+	archive := buildZip(t, zip.Deflate, testFile{name: "chrome-linux/chrome", body: "binary"})
+	// The HEAD request of the preflight announces an archive no disk can hold, the download itself has no Content-Length
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Length", "1125899906842624")
+			return
+		}
+		w.(http.Flusher).Flush()
+		w.Write(archive)
+	}))
+	defer srv.Close()
+
+	outPath := filepath.Join(t.TempDir(), "out")
+	d := newTestExtractor(srv.URL, outPath, WithPreflight(true), WithDiskSpaceCheck(true))
+	_, err := d.Run()
+	if !errors.Is(err, ErrInsufficientSpace) {
+		t.Skipf("Run returned %v instead of ErrInsufficientSpace, the disk space cannot be checked here", err)
+	}
+	_, err = d.ListContents()
+	if err != nil {
+		t.Errorf("ListContents returned %v", err)
+	}
+}
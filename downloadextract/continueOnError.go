@@ -0,0 +1,75 @@
+package downloadextract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrFilesFailed is wrapped by the error returned if files could not be written with SetContinueOnError.
+// The files are listed in the FileErrors of the Result.
+var ErrFilesFailed = errors.New("some files could not be extracted")
+
+// FileError describes an archive entry which could not be written to disk.
+type FileError struct {
+	// Entry is the name of the entry within the archive.
+	Entry string
+	// Path is the path the entry was supposed to be written to.
+	Path string
+	// Err is the cause of the failure.
+	Err error
+}
+
+func (e FileError) Error() string {
+	return fmt.Sprintf("archive entry \"%s\": %v", e.Entry, e.Err)
+}
+
+func (e FileError) Unwrap() error {
+	return e.Err
+}
+
+// WithContinueOnError is the Option equivalent of SetContinueOnError.
+func WithContinueOnError(b bool) Option {
+	return func(d *DownloadExtractor) {
+		d.continueOnError = b
+	}
+}
+
+// SetContinueOnError makes, when set to true, the extraction continue with the next entry if a file or directory cannot be written, like when it is locked or its permissions deny access.
+// Such failures are logged and collected in the FileErrors of the Result, and the run fails with an error wrapping ErrFilesFailed once the rest of the archive is extracted.
+// Errors of the archive itself, its download and the extraction limits still abort the run right away.
+// Since the run fails, RemoveOnFail deletes the extracted files unless SetMergeMode is enabled as well.
+// The default is to abort on the first failure.
+func (d *DownloadExtractor) SetContinueOnError(b bool) {
+	WithContinueOnError(b)(d)
+}
+
+// fileError returns err, which occurred while writing the entry fHdr to fPath, as a FileError, if the extraction may continue with the next entry.
+// ok is false if SetContinueOnError is disabled or err is not specific to the file.
+func (d *DownloadExtractor) fileError(fHdr *entry, fPath string, err error) (fileErr FileError, ok bool) {
+	if !d.continueOnError || !isFileError(err) {
+		return FileError{}, false
+	}
+	d.logger.Printf("Could not extract \"%s\", continuing: %v\n", fPath, err)
+	return FileError{Entry: fHdr.name, Path: fPath, Err: err}, true
+}
+
+// isFileError reports whether err was caused by the filesystem refusing to write a single file, as opposed to the archive, its download or the run failing.
+func isFileError(err error) bool {
+	var formatErr *FormatError
+	var transferErr *TransferError
+	if errors.As(err, &formatErr) || errors.As(err, &transferErr) || errors.Is(err, ErrLimitExceeded) || errors.Is(err, io.ErrClosedPipe) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pathErr *os.PathError
+	var linkErr *os.LinkError
+	return errors.As(err, &pathErr) || errors.As(err, &linkErr)
+}
+
+// filesFailedError returns the error of a run which could not write the files of errs, describing the first one.
+func filesFailedError(errs []FileError) error {
+	return fmt.Errorf("%w (%v in total): %v", ErrFilesFailed, len(errs), errs[0])
+}
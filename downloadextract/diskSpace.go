@@ -0,0 +1,64 @@
+package downloadextract
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fried-ice/chromiumup/internal/display"
+)
+
+// ErrInsufficientSpace is wrapped by the error returned if the disk space check of SetDiskSpaceCheck finds too little space for the archive.
+// Running out of space during the extraction is reported with the error of the operating system, like syscall.ENOSPC.
+var ErrInsufficientSpace = errors.New("insufficient disk space")
+
+// errSpaceUnknown is returned by freeSpace on platforms where the available disk space cannot be determined.
+var errSpaceUnknown = errors.New("available disk space cannot be determined on this platform")
+
+// WithDiskSpaceCheck is the Option equivalent of SetDiskSpaceCheck.
+func WithDiskSpaceCheck(b bool) Option {
+	return func(d *DownloadExtractor) {
+		d.spaceCheck = b
+	}
+}
+
+// SetDiskSpaceCheck enables, when set to true, a check for sufficient disk space before the archive is downloaded.
+// As the size of the extracted files is not known upfront, the size of the archive serves as a lower bound of the required space.
+func (d *DownloadExtractor) SetDiskSpaceCheck(b bool) {
+	WithDiskSpaceCheck(b)(d)
+}
+
+// checkDiskSpace returns an error if less than required bytes are available on the filesystem of the output path.
+// The check is skipped if the required space or the available space is unknown.
+func (d *DownloadExtractor) checkDiskSpace(required int64) error {
+	if !d.spaceCheck || required <= 0 {
+		return nil
+	}
+
+	// The output path usually does not exist yet, so look for the closest existing parent
+	path, err := filepath.Abs(d.outPath)
+	if err != nil {
+		return err
+	}
+	for !pathExists(path) && filepath.Dir(path) != path {
+		path = filepath.Dir(path)
+	}
+
+	available, err := freeSpace(path)
+	if err == errSpaceUnknown {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if uint64(required) > available {
+		return fmt.Errorf("%w: need ~%s, have %s", ErrInsufficientSpace, display.FormatBytes(uint64(required)), display.FormatBytes(available))
+	}
+	return nil
+}
+
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return !os.IsNotExist(err)
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package downloadextract
+
+// freeSpace always fails with errSpaceUnknown, as there is no supported way to query the available space.
+func freeSpace(path string) (uint64, error) {
+	return 0, errSpaceUnknown
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin || freebsd
+
+package downloadextract
+
+import "syscall"
+
+// freeSpace returns the number of bytes available to unprivileged users on the filesystem containing path.
+func freeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	err := syscall.Statfs(path, &stat)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -1,24 +1,123 @@
 package downloadextract
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
-
-	"github.com/krolaw/zipstream"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// DownloadExtractor is a stateful utility to download zip archives via http(s) and extract them.
-// Because of the the use of go pipes and routines, zip files are streamed right at the beginning of the download, so there is no need to buffer the complete archive first.
+// partialFileExt is appended to the path of a file while it is written.
+const partialFileExt = ".partial"
+
+// ErrEmptyArchive is returned if an archive does not contain any file below the omitted top directories.
+// Such an archive is most likely broken, so it must not replace a working installation.
+var ErrEmptyArchive = errors.New("archive contains no files")
+
+// ErrTruncated is wrapped by the error returned if the server sent less data than announced by its Content-Length header.
+var ErrTruncated = errors.New("download is truncated")
+
+// ErrConcurrentRun is returned if a DownloadExtractor is run while another run of it is still in progress.
+var ErrConcurrentRun = errors.New("DownloadExtractor is already running")
+
+// DownloadExtractor is a stateful utility to download zip or tar.gz archives via http(s) and extract them.
+// Because of the the use of go pipes and routines, archives are streamed right at the beginning of the download, so there is no need to buffer the complete archive first.
+// A DownloadExtractor may be run any number of times, one after another, as every run starts from scratch and all statistics are part of its Result.
+// It must not be reconfigured during a run, and concurrent runs fail with ErrConcurrentRun.
 type DownloadExtractor struct {
+	running           int32
 	url               string
 	outPath           string
 	omittedParentDirs int
 	removeOnFail      bool
+	progress          ProgressFunc
+	client            *http.Client
+	retries           int
+	retryDelay        time.Duration
+	resumable         bool
+	checksumAlgo      string
+	checksum          string
+	format            Format
+	verbose           bool
+	logger            Logger
+	concurrency       int
+	maxBytesPerSecond int64
+	spaceCheck        bool
+	statusOutput      io.Writer
+	merge             bool
+	connections       int
+	dirMode           os.FileMode
+	fileMode          os.FileMode
+	archiveOnly       bool
+	deadline          time.Duration
+	manifestOut       io.Writer
+	manifest          *manifest
+	userAgent         string
+	include           []string
+	exclude           []string
+	fs                Filesystem
+	maxTotalBytes     int64
+	maxFiles          int
+	events            chan<- Event
+	skipUnchanged     bool
+	preflight         bool
+	bufferSize        int
+	flatten           bool
+	collisionPolicy   CollisionPolicy
+	flatPaths         map[string]string
+	flatUsed          map[string]bool
+	ifNoneMatch       string
+	ifModifiedSince   string
+	continueOnError   bool
+	bufferToDisk      bool
+	tempFile          string
+	extractProgress   ExtractProgressFunc
+	listing           bool
+	buffers           sync.Pool
+}
+
+// Result contains statistics about a finished run.
+type Result struct {
+	// FilesWritten is the number of extracted files, including symbolic links.
+	FilesWritten int
+	// DirsCreated is the number of directory entries of the archive.
+	DirsCreated int
+	// TotalBytes is the sum of the sizes of all extracted files.
+	TotalBytes int64
+	// FilesSkipped is the number of files which were left untouched, as they were unchanged, see SetSkipUnchanged.
+	FilesSkipped int
+	// Duration is the time the complete run took.
+	Duration time.Duration
+	// NetworkWait is the time the extraction of a streamed archive spent waiting for downloaded data.
+	// If the archive is downloaded completely before it is extracted, it is the time the download took.
+	NetworkWait time.Duration
+	// DiskWait is the time the download of a streamed archive spent waiting for the extraction to take the data, which is mostly spent writing files.
+	// A high DiskWait means the run is limited by the disk rather than the network.
+	DiskWait time.Duration
+	// ContentLength is the size of the archive as announced by the server, or -1 if it did not announce one.
+	ContentLength int64
+	// ServerMD5 is the hex encoded md5 digest of the archive sent by the server in the X-Goog-Hash header, if any.
+	ServerMD5 string
+	// ServerCRC32C is the hex encoded CRC32C checksum of the archive sent by the server in the X-Goog-Hash header, if any.
+	ServerCRC32C string
+	// ETag and LastModified are the validators of the archive sent by the server, if any, to be passed to SetIfModified of a later run.
+	ETag         string
+	LastModified string
+	// FileErrors lists the files which could not be written, see SetContinueOnError.
+	FileErrors []FileError
 }
 
 // NewDownloadExtractor creates a new DownloadExtractor.
@@ -29,110 +128,753 @@ func NewDownloadExtractor(url string, outPath string) *DownloadExtractor {
 		outPath:           outPath,
 		omittedParentDirs: 0,
 		removeOnFail:      false,
+		format:            Auto,
+		logger:            stdoutLogger{},
+		client:            NewHTTPClient(),
+		dirMode:           defaultDirMode,
+		fileMode:          defaultFileMode,
+		fs:                osFilesystem{},
+		maxTotalBytes:     defaultMaxTotalBytes,
+		maxFiles:          defaultMaxFiles,
+		bufferSize:        defaultBufferSize,
+	}
+}
+
+// WithOmitTopDirs is the Option equivalent of OmitTopDirs.
+func WithOmitTopDirs(count int) Option {
+	return func(d *DownloadExtractor) {
+		d.omittedParentDirs = count
 	}
 }
 
 // OmitTopDirs sets the number of top hierarchy directories to be omitted on extraction time.
 // This is useful, if your directory of interest is included in a wrapper directory you do not actually need.
 func (d *DownloadExtractor) OmitTopDirs(count int) {
-	d.omittedParentDirs = count
+	WithOmitTopDirs(count)(d)
+}
+
+// WithRemoveOnFail is the Option equivalent of RemoveOnFail.
+func WithRemoveOnFail(b bool) Option {
+	return func(d *DownloadExtractor) {
+		d.removeOnFail = b
+	}
 }
 
 // RemoveOnFail enables, when set to true, the removal of any created files and directories if any error occurs.
+// It has no effect in merge mode, see SetMergeMode.
 func (d *DownloadExtractor) RemoveOnFail(b bool) {
-	d.removeOnFail = b
+	WithRemoveOnFail(b)(d)
+}
+
+// WithProgress is the Option equivalent of SetProgressCallback.
+func WithProgress(callback func(bytesDownloaded, totalBytes int64)) Option {
+	return func(d *DownloadExtractor) {
+		d.progress = callback
+	}
+}
+
+// SetProgressCallback registers a function which is called periodically while the archive is downloaded.
+// The total size is taken from the Content-Length header of the response and is -1 if the server does not send one.
+func (d *DownloadExtractor) SetProgressCallback(callback func(bytesDownloaded, totalBytes int64)) {
+	WithProgress(callback)(d)
+}
+
+// WithHTTPClient is the Option equivalent of SetHTTPClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *DownloadExtractor) {
+		d.client = client
+	}
+}
+
+// SetHTTPClient sets the http client used to download the archive.
+// Note that the Timeout of client applies to the complete download, so an aggressive value will abort large downloads.
+func (d *DownloadExtractor) SetHTTPClient(client *http.Client) {
+	WithHTTPClient(client)(d)
+}
+
+// WithVerbose is the Option equivalent of SetVerbose.
+func WithVerbose(b bool) Option {
+	return func(d *DownloadExtractor) {
+		d.verbose = b
+	}
+}
+
+// SetVerbose enables, when set to true, printing a line for every extracted file.
+// Otherwise only a summary is printed once the archive is extracted.
+func (d *DownloadExtractor) SetVerbose(b bool) {
+	WithVerbose(b)(d)
+}
+
+// WithDeadline is the Option equivalent of SetDeadline.
+func WithDeadline(timeout time.Duration) Option {
+	return func(d *DownloadExtractor) {
+		d.deadline = timeout
+	}
+}
+
+// SetDeadline limits the time a run may take in total, measured from the start of Run.
+// If the download and extraction do not finish in time, the run is aborted with an error wrapping context.DeadlineExceeded.
+// A value of 0 means no limit, which is the default.
+func (d *DownloadExtractor) SetDeadline(timeout time.Duration) {
+	WithDeadline(timeout)(d)
 }
 
 // Run initiates the process for downloading and extracting the file.
-func (d *DownloadExtractor) Run() {
+// Statistics about the extracted files are returned, as well as any error occurring while downloading or extracting.
+func (d *DownloadExtractor) Run() (Result, error) {
+	return d.RunContext(context.Background())
+}
+
+// RunContext is like Run, but aborts downloading and extracting as soon as ctx is done.
+// In this case the error of ctx is returned.
+func (d *DownloadExtractor) RunContext(ctx context.Context) (Result, error) {
+	return d.run(ctx, func(ctx context.Context, result *Result) error {
+		if d.resumable {
+			return d.runResumable(ctx, result)
+		}
+		if d.connections > 1 && !d.archiveOnly {
+			parallel, err := d.runParallel(ctx, result)
+			if parallel || err != nil {
+				return err
+			}
+		}
+		if d.bufferToDisk && !d.archiveOnly {
+			return d.runBuffered(ctx, result)
+		}
+
+		return d.stream(ctx, result, func(archive io.Reader) error {
+			if d.archiveOnly {
+				return d.save(archive, result)
+			}
+			return d.extract(ctx, archive, result)
+		})
+	})
+}
+
+// stream downloads the archive and hands the data to consume while it is received.
+func (d *DownloadExtractor) stream(ctx context.Context, result *Result, consume func(archive io.Reader) error) error {
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	pR, pW := io.Pipe()
-	go d.fetch(pW)
-	d.extract(pR)
+	fetched := make(chan error, 1)
+	go func() {
+		err := d.fetch(fetchCtx, pW, result)
+		pW.CloseWithError(err)
+		fetched <- err
+	}()
+	err := consume(&timedReader{r: pR, wait: &result.NetworkWait})
+	if err == nil {
+		// Consume the rest of the archive, so errors detected by fetch after the last entry are not lost.
+		_, err = io.Copy(ioutil.Discard, pR)
+	}
+	pR.Close()
+	if err != nil {
+		cancel()
+	}
+	return fetchError(err, <-fetched)
+}
+
+// run performs a single run with do, applying the settings common to all kinds of runs, like the deadline and RemoveOnFail.
+func (d *DownloadExtractor) run(ctx context.Context, do func(ctx context.Context, result *Result) error) (Result, error) {
+	if !atomic.CompareAndSwapInt32(&d.running, 0, 1) {
+		return Result{}, ErrConcurrentRun
+	}
+	defer atomic.StoreInt32(&d.running, 0)
+	d.emit(Event{Type: EventStart})
+
+	start := time.Now()
+	if d.deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.deadline)
+		defer cancel()
+	}
+	result := Result{ContentLength: -1}
+	err := do(ctx, &result)
 
+	if d.deadline > 0 && errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("run did not finish within %v: %w", d.deadline, err)
+	}
+
+	// Delete extracted files on failure if this behavior is enabled via RemoveOnFail
+	if err != nil && d.removeOnFail && !d.merge {
+		e := d.fs.RemoveAll(d.outPath)
+		if e == nil {
+			d.logger.Printf("Removed already extracted files of partially downloaded archive\n")
+		}
+	}
+	result.Duration = time.Since(start)
+	if err != nil {
+		d.emit(Event{Type: EventError, Err: err, Result: result})
+	} else {
+		d.emit(Event{Type: EventDone, Result: result})
+	}
+	return result, err
 }
 
-func (d *DownloadExtractor) fetch(pW *io.PipeWriter) {
-	defer pW.Close()
+// fetch sends the http request and copies the response body into pW.
+// Errors of receiving the archive are returned as TransferErrors.
+func (d *DownloadExtractor) fetch(ctx context.Context, pW *io.PipeWriter, result *Result) error {
+	total := int64(-1)
+	if d.preflight {
+		probe, err := d.ProbeContext(ctx)
+		if err != nil {
+			return transferError(ctx, err)
+		}
+		total = probe.ContentLength
+	}
 
-	resp, err := http.Get(d.url)
+	resp, err := d.get(ctx, d.url, d.conditionalHeader())
 	if err != nil {
-		panic(err)
+		return transferError(ctx, err)
 	}
 	if resp.Body == nil {
-		panic(errors.New("HTTP response body is nil"))
+		return errors.New("HTTP response body is nil")
 	}
 
 	defer resp.Body.Close()
-	_, err = io.Copy(pW, resp.Body)
+	if resp.StatusCode == http.StatusNotModified {
+		return ErrNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+	setValidators(resp, result)
+	if resp.ContentLength >= 0 {
+		total = resp.ContentLength
+	}
+	result.ContentLength = total
+	result.ServerMD5, result.ServerCRC32C = parseGoogHash(resp.Header)
+	// Listing the contents does not write anything, so it needs no space
+	if !d.listing {
+		err = d.checkDiskSpace(total)
+		if err != nil {
+			return err
+		}
+	}
+
+	counter := &countingReader{r: resp.Body}
+	var body io.Reader = counter
+	if d.maxBytesPerSecond > 0 {
+		body = newThrottledReader(body, d.maxBytesPerSecond)
+	}
+	if progress := d.progressFunc(); progress != nil {
+		body = newProgressReader(body, total, progress)
+	}
+	// Progress refers to the bytes transferred, the checksum to the decoded archive
+	body, err = decodeContent(resp, body)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	var h hash.Hash
+	if d.checksum != "" {
+		h, err = newHash(d.checksumAlgo)
+		if err != nil {
+			return err
+		}
+		body = io.TeeReader(body, h)
+	}
+	_, err = d.copyBuffer(&timedWriter{w: pW, wait: &result.DiskWait}, body)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
 	}
+	if counter.err != nil {
+		return transferError(ctx, counter.readError(resp.ContentLength))
+	}
+	if err != nil {
+		return err
+	}
+	err = checkLength(counter.n, resp.ContentLength)
+	if err != nil {
+		return transferError(ctx, err)
+	}
+	if h != nil {
+		return d.verifyHash(h)
+	}
+	return nil
+}
+
+// ExtractError is returned by Run if the extraction fails after it has begun.
+// It describes what has been written up to the failure, so callers can decide whether to retry or clean up.
+type ExtractError struct {
+	// Entry is the name of the archive entry being processed when the failure occurred, or empty if there was none yet.
+	Entry string
+	// FilesWritten is the number of files extracted before the failure.
+	// With SetConcurrency, some of them may have been queued for writing only.
+	FilesWritten int
+	// BytesWritten is the sum of the sizes of these files.
+	BytesWritten int64
+	// Err is the cause of the failure.
+	Err error
 }
 
-func (d *DownloadExtractor) extract(pR *io.PipeReader) {
-	defer pR.Close()
+func (e *ExtractError) Error() string {
+	return fmt.Sprintf("extraction failed after %v files with %v bytes: %v", e.FilesWritten, e.BytesWritten, e.Err)
+}
 
-	// Delete extracted files on panic if this behavior is enabled via RemoveOnFail
-	if d.removeOnFail {
-		defer func() {
-			if err := recover(); err != nil {
-				e := os.RemoveAll(d.outPath)
-				if e == nil {
-					println("Removed already extracted files of partially downloaded archive")
-				}
-				panic(err)
-			}
-		}()
+func (e *ExtractError) Unwrap() error {
+	return e.Err
+}
+
+// extract reads an archive from r and writes its contents below the output path.
+// The extracted files and directories are counted in result. Errors are wrapped in an ExtractError.
+func (d *DownloadExtractor) extract(ctx context.Context, r io.Reader, result *Result) error {
+	var current string
+	err := d.extractEntries(ctx, r, result, &current)
+	if err != nil {
+		return &ExtractError{Entry: current, FilesWritten: result.FilesWritten, BytesWritten: result.TotalBytes, Err: err}
+	}
+	return nil
+}
+
+// extractEntries does the work of extract, storing the name of the entry currently processed in current.
+func (d *DownloadExtractor) extractEntries(ctx context.Context, r io.Reader, result *Result, current *string) error {
+	err := d.checkPatterns()
+	if err != nil {
+		return err
+	}
+	aR, err := newArchiveReader(r, d.format)
+	if err != nil {
+		return formatError(err)
 	}
 
-	zR := zipstream.NewReader(pR)
+	// Files overwriting each other must not be written concurrently
+	var pool *writePool
+	if d.concurrency > 1 && !(d.flatten && d.collisionPolicy == CollisionOverwrite) {
+		pool = d.newWritePool(d.concurrency)
+		defer pool.wait()
+	}
 
-	fHdr, err := zR.Next()
-	for ; err != io.EOF; fHdr, err = zR.Next() {
+	d.manifest = nil
+	if d.manifestOut != nil {
+		d.manifest = newManifest()
+	}
+	d.flatPaths, d.flatUsed = map[string]string{}, map[string]bool{}
+
+	var status *statusPrinter
+	if d.statusOutput != nil {
+		status = newStatusPrinter(d.statusOutput)
+	}
+	progress := d.newExtractProgress(r)
+
+	var dirs []*entry
+	fHdr, err := aR.Next()
+	for ; err != io.EOF; fHdr, err = aR.Next() {
+		if status != nil {
+			status.update(result.FilesWritten, result.TotalBytes)
+		}
+		progress.update(result)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		if err != nil {
-			panic(err)
+			return formatError(err)
 		}
+		*current = fHdr.name
 
-		// Remove top folders if necessary
-		shortenedPath := ""
-		if d.omittedParentDirs != 0 {
-			shortenedPath = strings.Join(strings.SplitAfterN(fHdr.Name, "/", d.omittedParentDirs+1)[d.omittedParentDirs:], "")
-		} else {
-			shortenedPath = fHdr.Name
+		// Files within the omitted top folders have no place in the output path
+		if !fHdr.info.IsDir() && d.shortenPath(fHdr.name) == "" {
+			continue
+		}
+		if !d.included(fHdr.name) || (fHdr.info.IsDir() && d.flatten) {
+			continue
+		}
+		fPath, err := d.outputPath(fHdr.name)
+		if err != nil {
+			return fmt.Errorf("archive entry \"%s\": %w", fHdr.name, err)
 		}
 
-		fPath := filepath.Join(d.outPath, shortenedPath)
+		if fHdr.info.IsDir() { // Create directory ...
+			err := d.fs.MkdirAll(fPath, d.dirMode)
+			if fileErr, ok := d.fileError(fHdr, fPath, err); ok {
+				result.FileErrors = append(result.FileErrors, fileErr)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			dirs = append(dirs, fHdr)
+			result.DirsCreated++
+			continue
+		}
 
-		if fHdr.FileInfo().IsDir() { // Create directory ...
-			err := os.MkdirAll(fPath, os.ModePerm)
+		err = d.checkMaxFiles(result.FilesWritten)
+		if err != nil {
+			return err
+		}
+		content := d.limitContent(formatReader{aR}, result.TotalBytes)
+		if fHdr.info.Mode()&os.ModeSymlink != 0 { // Symbolic link ...
+			err := d.symlink(fHdr, content, fPath)
+			if fileErr, ok := d.fileError(fHdr, fPath, err); ok {
+				result.FileErrors = append(result.FileErrors, fileErr)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("archive entry \"%s\": %w", fHdr.name, err)
+			}
+			result.FilesWritten++
+			d.emit(Event{Type: EventFileWritten, Path: fPath})
+		} else if d.unchanged(fPath, fHdr) { // ... or regular file which is already in place ...
+			result.FilesSkipped++
+		} else if pool != nil { // ... or regular file, written by the pool ...
+			data, err := ioutil.ReadAll(content)
 			if err != nil {
-				panic(err)
+				return err
 			}
+			err = pool.submit(writeJob{fPath: fPath, fHdr: fHdr, data: data})
+			if err != nil {
+				return err
+			}
+			result.FilesWritten++
+			result.TotalBytes += int64(len(data))
 		} else { // ... or regular file
+			fSize, err := d.writeFile(fPath, fHdr, content)
+			if fileErr, ok := d.fileError(fHdr, fPath, err); ok {
+				result.FileErrors = append(result.FileErrors, fileErr)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			result.FilesWritten++
+			result.TotalBytes += fSize
+		}
+	}
+	if pool != nil {
+		err = pool.wait()
+		if err != nil {
+			return err
+		}
+		result.FilesWritten -= len(pool.fileErrors)
+		result.TotalBytes -= pool.failedBytes
+		result.FileErrors = append(result.FileErrors, pool.fileErrors...)
+	}
+	progress.update(result)
+	if status != nil {
+		status.finish()
+	}
+	if result.FilesWritten == 0 && len(result.FileErrors) == 0 {
+		return ErrEmptyArchive
+	}
+	if !d.verbose {
+		d.logger.Printf("Extracted %v files with a total of %v bytes\n", result.FilesWritten, result.TotalBytes)
+	}
 
-			err := os.MkdirAll(filepath.Dir(fPath), os.ModePerm)
+	// Zip archives only reveal file modes in the central directory at their very end
+	if t, ok := aR.(metadataTrailer); ok {
+		entries, err := t.trailer()
+		if err != nil {
+			d.logger.Printf("Could not read file modes from archive: %v\n", err)
+		} else {
+			err = d.applyTrailer(entries)
 			if err != nil {
-				panic(err)
+				return err
 			}
+			// The central directory lists the directories of the stream once more, so they replace rather than add to them
+			dirs = dirs[:0]
+			for _, fHdr := range entries {
+				if fHdr.info.IsDir() && d.included(fHdr.name) && !d.flatten {
+					dirs = append(dirs, fHdr)
+				}
+			}
+			result.DirsCreated = len(dirs)
+		}
+	}
+
+	if d.manifest != nil {
+		err = d.manifest.write(d.manifestOut, d.fs, d.outPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Directory times are set last, as writing their children modifies them
+	for _, fHdr := range dirs {
+		fPath, err := d.outputPath(fHdr.name)
+		if err != nil {
+			return fmt.Errorf("archive entry \"%s\": %w", fHdr.name, err)
+		}
+		// A directory which has been replaced by a symbolic link is not followed
+		fInfo, err := d.fs.Lstat(fPath)
+		if err != nil || !fInfo.IsDir() {
+			continue
+		}
+		err = d.fs.Chtimes(fPath, fHdr.info.ModTime(), fHdr.info.ModTime())
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(result.FileErrors) > 0 {
+		*current = ""
+		return filesFailedError(result.FileErrors)
+	}
+	return nil
+}
+
+// writeFile writes the contents of the regular file entry fHdr read from r to fPath and returns its size.
+// The contents are written to a sibling file first, which replaces fPath once it is complete, so fPath is never left half-written.
+func (d *DownloadExtractor) writeFile(fPath string, fHdr *entry, r io.Reader) (int64, error) {
+	err := d.fs.MkdirAll(filepath.Dir(fPath), d.dirMode)
+	if err != nil {
+		return 0, err
+	}
+
+	// A symbolic link left at partPath, e.g. by an entry of the archive, must not be written through
+	partPath := fPath + partialFileExt
+	d.fs.Remove(partPath)
+	outFile, err := d.fs.OpenFile(partPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, d.filePerm(fHdr))
+	if err != nil {
+		return 0, err
+	}
+
+	var h hash.Hash
+	if d.manifest != nil {
+		h = sha256.New()
+		r = io.TeeReader(r, h)
+	}
+	fSize, err := d.copyBuffer(outFile, r)
+	if e := outFile.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = d.fs.Chtimes(partPath, fHdr.info.ModTime(), fHdr.info.ModTime())
+	}
+	if err == nil {
+		// Renaming replaces a symbolic link at fPath rather than writing through it
+		err = d.fs.Rename(partPath, fPath)
+	}
+	if err != nil {
+		d.fs.Remove(partPath)
+		return 0, err
+	}
+	if h != nil {
+		rel := d.shortenPath(fHdr.name)
+		if d.flatten {
+			rel = filepath.Base(fPath)
+		}
+		d.manifest.add(rel, hex.EncodeToString(h.Sum(nil)))
+	}
+	d.emit(Event{Type: EventFileWritten, Path: fPath, Size: fSize})
+
+	if d.verbose {
+		absPath, err := filepath.Abs(fPath)
+		if err != nil {
+			absPath = fPath
+		}
+		if fSize == 0 {
+			d.logger.Printf("Created empty file \"%s\"\n", absPath)
+		} else {
+			d.logger.Printf("Wrote %v bytes to file \"%s\"\n", fSize, absPath)
+		}
+	}
+	return fSize, nil
+}
+
+// outputPath returns the path an archive entry called name is extracted to.
+// An error is returned if the path is not located within the output path, or below a symbolic link within it.
+// This is checked after the top folders have been omitted, as the remainder of a name like "top/../../evil" escapes even if the name as a whole does not.
+func (d *DownloadExtractor) outputPath(name string) (string, error) {
+	shortened := path.Clean(d.shortenPath(name))
+	if shortened == ".." || strings.HasPrefix(shortened, "../") || filepath.VolumeName(filepath.FromSlash(shortened)) != "" {
+		return "", errors.New("path escapes the output directory")
+	}
+	if d.flatten {
+		return d.flatPath(name, shortened)
+	}
+	fPath := filepath.Join(d.outPath, shortened)
+	err := d.checkContained(fPath)
+	if err != nil {
+		return "", err
+	}
+	return fPath, d.checkParents(fPath)
+}
 
-			outFile, err := os.OpenFile(fPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fHdr.Mode())
+// shortenPath removes the top folders to be omitted from the archive entry name.
+// Backslashes are treated as separators and leading slashes are ignored.
+// If name does not have more elements than there are folders to be omitted, an empty string is returned.
+func (d *DownloadExtractor) shortenPath(name string) string {
+	name = strings.TrimLeft(strings.Replace(name, "\\", "/", -1), "/")
+	if d.omittedParentDirs <= 0 {
+		return name
+	}
+	parts := strings.SplitAfterN(name, "/", d.omittedParentDirs+1)
+	if len(parts) <= d.omittedParentDirs {
+		return ""
+	}
+	return parts[d.omittedParentDirs]
+}
+
+// applyTrailer corrects already extracted files according to entries, which carry the complete metadata.
+// Regular files get their final mode and modification time and files which turn out to be symbolic links are replaced by them.
+func (d *DownloadExtractor) applyTrailer(entries []*entry) error {
+	for _, fHdr := range entries {
+		if fHdr.info.IsDir() || d.shortenPath(fHdr.name) == "" || !d.included(fHdr.name) {
+			continue
+		}
+		fPath, err := d.outputPath(fHdr.name)
+		if err != nil {
+			return fmt.Errorf("archive entry \"%s\": %w", fHdr.name, err)
+		}
+		fInfo, err := d.fs.Lstat(fPath)
+		if err != nil || !fInfo.Mode().IsRegular() {
+			continue
+		}
+
+		if fHdr.info.Mode()&os.ModeSymlink != 0 {
+			target, err := d.fs.ReadFile(fPath)
+			if err != nil {
+				return err
+			}
+			err = d.symlink(fHdr, bytes.NewReader(target), fPath)
 			if err != nil {
-				panic(err)
+				return fmt.Errorf("archive entry \"%s\": %w", fHdr.name, err)
 			}
-			defer outFile.Close()
+			continue
+		}
 
-			fSize, err := io.Copy(outFile, zR)
+		if preserveModes && fInfo.Mode().Perm() != d.filePerm(fHdr) {
+			err = d.fs.Chmod(fPath, d.filePerm(fHdr))
 			if err != nil {
-				panic(err)
+				return err
 			}
+		}
+		err = d.fs.Chtimes(fPath, fHdr.info.ModTime(), fHdr.info.ModTime())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// symlink creates a symbolic link at fPath for the archive entry fHdr.
+// Zip archives store the link target as content of the entry, so it is read from r if the entry does not carry it.
+func (d *DownloadExtractor) symlink(fHdr *entry, r io.Reader, fPath string) error {
+	target := fHdr.linkname
+	if target == "" {
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		target = string(b)
+	}
+
+	// The link must not point outside of the output path either
+	if filepath.IsAbs(target) {
+		return errors.New("symbolic link has an absolute target")
+	}
+	err := d.checkContained(filepath.Join(filepath.Dir(fPath), target))
+	if err == nil {
+		err = d.checkTarget(filepath.Dir(fPath), target)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = d.fs.MkdirAll(filepath.Dir(fPath), d.dirMode)
+	if err != nil {
+		return err
+	}
+	d.fs.Remove(fPath)
+	return d.fs.Symlink(target, fPath)
+}
+
+// checkContained returns an error if path is not located within the output path.
+// This protects against archive entries escaping the output path with ".." elements.
+func (d *DownloadExtractor) checkContained(path string) error {
+	root, err := filepath.Abs(d.outPath)
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if abs != root && !strings.HasPrefix(abs, root+string(filepath.Separator)) {
+		return errors.New("path escapes the output directory")
+	}
+	return nil
+}
 
-			absPath, err := filepath.Abs(fPath)
-			if err == nil {
-				fmt.Printf("Wrote %v bytes to file \"%s\"\n", fSize, absPath)
-			} else {
-				fmt.Printf("Wrote %v bytes to file \"%s\"\n", fSize, fPath)
+// checkParents returns an error if one of the existing directories between the output path and fPath is a symbolic link.
+// Links created by earlier entries of the archive are checked by checkContained on their own, but a chain of them like "a/b -> .." and "a/b/c -> .." leads outside of the output path.
+func (d *DownloadExtractor) checkParents(fPath string) error {
+	rel, err := filepath.Rel(d.outPath, filepath.Dir(fPath))
+	if err != nil || rel == "." {
+		return err
+	}
+	dir := d.outPath
+	for _, elem := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, elem)
+		fInfo, err := d.fs.Lstat(dir)
+		if os.IsNotExist(err) {
+			// The remaining directories are created by the entry itself
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if fInfo.Mode()&os.ModeSymlink != 0 {
+			return errors.New("path is located below a symbolic link")
+		}
+	}
+	return nil
+}
+
+// checkTarget returns an error if the link target, relative to dir, leaves a symbolic link with a ".." element.
+// ".." is applied to the target of such a link rather than lexically, so checkContained does not hold for it.
+func (d *DownloadExtractor) checkTarget(dir string, target string) error {
+	// target must not be cleaned, which would apply ".." lexically
+	for _, elem := range strings.Split(filepath.FromSlash(target), string(filepath.Separator)) {
+		if elem == "" || elem == "." {
+			continue
+		}
+		if elem == ".." {
+			fInfo, err := d.fs.Lstat(dir)
+			if err == nil && fInfo.Mode()&os.ModeSymlink != 0 {
+				return errors.New("symbolic link target leaves another symbolic link")
 			}
+			dir = filepath.Dir(dir)
+		} else {
+			dir = filepath.Join(dir, elem)
 		}
 	}
+	return nil
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+	// err is the last error of reading from r other than io.EOF.
+	err error
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	if err != nil && err != io.EOF {
+		c.err = err
+	}
+	return n, err
+}
 
+// readError returns the error of reading from r, which wraps ErrTruncated if fewer than contentLength bytes have been read.
+// net/http reports a connection closed before the announced length as io.ErrUnexpectedEOF only.
+func (c *countingReader) readError(contentLength int64) error {
+	if c.err == nil {
+		return nil
+	}
+	if err := checkLength(c.n, contentLength); err != nil {
+		return fmt.Errorf("%w (%v)", err, c.err)
+	}
+	return c.err
+}
+
+// checkLength returns an error wrapping ErrTruncated if the n bytes received fall short of the announced contentLength.
+// A negative contentLength means the length is unknown.
+func checkLength(n int64, contentLength int64) error {
+	if contentLength >= 0 && n < contentLength {
+		return fmt.Errorf("%w: received %v of %v bytes", ErrTruncated, n, contentLength)
+	}
+	return nil
 }
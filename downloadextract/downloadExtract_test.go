@@ -0,0 +1,555 @@
+package downloadextract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTime is the modification time of all entries of the test archives.
+var testTime = time.Date(2020, 5, 17, 12, 30, 0, 0, time.UTC)
+
+// testFile is an entry of a test archive.
+// Names ending with a slash are directories, a link makes the entry a symbolic link.
+type testFile struct {
+	name string
+	body string
+	mode os.FileMode
+	link string
+}
+
+func (f testFile) fileMode() os.FileMode {
+	mode := f.mode
+	switch {
+	case strings.HasSuffix(f.name, "/"):
+		if mode == 0 {
+			mode = 0755
+		}
+		mode |= os.ModeDir
+	case f.link != "":
+		mode = os.ModeSymlink | 0777
+	case mode == 0:
+		mode = 0644
+	}
+	return mode
+}
+
+// buildZip returns a zip archive of files, compressed with method.
+// Stored entries carry their sizes in the local header, like those of the Chromium archives, deflated ones are followed by a data descriptor.
+func buildZip(t testing.TB, method uint16, files ...testFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zW := zip.NewWriter(&buf)
+	for _, f := range files {
+		hdr := &zip.FileHeader{Name: f.name, Method: method, Modified: testTime}
+		hdr.SetMode(f.fileMode())
+		body := f.body
+		if f.link != "" {
+			body = f.link
+		}
+		var w io.Writer
+		var err error
+		if method == zip.Store {
+			hdr.CRC32 = crc32.ChecksumIEEE([]byte(body))
+			hdr.CompressedSize64, hdr.UncompressedSize64 = uint64(len(body)), uint64(len(body))
+			w, err = zW.CreateRaw(hdr)
+		} else {
+			w, err = zW.CreateHeader(hdr)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = w.Write([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := zW.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// buildTarGz returns a gzip compressed tar archive of files.
+func buildTarGz(t testing.TB, files ...testFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gzW := gzip.NewWriter(&buf)
+	tW := tar.NewWriter(gzW)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.name, Mode: int64(f.fileMode().Perm()), Size: int64(len(f.body)), ModTime: testTime}
+		switch mode := f.fileMode(); {
+		case mode.IsDir():
+			hdr.Typeflag = tar.TypeDir
+		case mode&os.ModeSymlink != 0:
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, f.link, 0
+		default:
+			hdr.Typeflag = tar.TypeReg
+		}
+		err := tW.WriteHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			_, err = tW.Write([]byte(f.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tW.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzW.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// randomBody returns n bytes of incompressible content.
+func randomBody(n int) string {
+	b := make([]byte, n)
+	rand.New(rand.NewSource(int64(n))).Read(b)
+	return string(b)
+}
+
+// testETag is the ETag of all archives served by serveArchive.
+const testETag = `"v1"`
+
+// serveArchive serves data at the root of a test server, which supports range and conditional requests, and returns its URL.
+func serveArchive(t testing.TB, data []byte) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", testETag)
+		http.ServeContent(w, r, "", testTime, bytes.NewReader(data))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// serveTruncated serves the first n bytes of data, but announces all of them, so the connection ends in the middle of the archive.
+func serveTruncated(t testing.TB, data []byte, n int) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Write(data[:n])
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// cutWriter passes the first n bytes of a response body on and fails afterwards, so the response ends before its announced length.
+type cutWriter struct {
+	http.ResponseWriter
+	n int
+}
+
+func (c *cutWriter) Write(b []byte) (int, error) {
+	if len(b) > c.n {
+		n, _ := c.ResponseWriter.Write(b[:c.n])
+		c.n -= n
+		return n, errors.New("response cut")
+	}
+	n, err := c.ResponseWriter.Write(b)
+	c.n -= n
+	return n, err
+}
+
+// newTestExtractor returns a DownloadExtractor which does not log anything.
+func newTestExtractor(url string, outPath string, opts ...Option) *DownloadExtractor {
+	return New(url, outPath, append([]Option{WithLogger(log.New(ioutil.Discard, "", 0))}, opts...)...)
+}
+
+// readTree returns the contents of the directory at root, keyed by slash separated relative paths.
+// Directories are listed with a trailing slash and no content, symbolic links with "-> " and their target.
+func readTree(t testing.TB, root string) map[string]string {
+	t.Helper()
+	tree := map[string]string{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == root {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		switch {
+		case info.IsDir():
+			tree[rel+"/"] = ""
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			tree[rel] = "-> " + filepath.ToSlash(target)
+		default:
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			tree[rel] = string(b)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+// assertNotExist fails the test if path exists, e.g. as it has not been cleaned up.
+func assertNotExist(t testing.TB, path string) {
+	t.Helper()
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("%s exists: %v", path, err)
+	}
+}
+
+func TestRunOmitTopDirs(t *testing.T) {
+	archive := buildZip(t, zip.Deflate,
+		testFile{name: "README", body: "top"},
+		testFile{name: "chrome-linux/"},
+		testFile{name: "chrome-linux/chrome", body: "binary", mode: 0755},
+		testFile{name: "chrome-linux/locales/"},
+		testFile{name: "chrome-linux/locales/en-US.pak", body: "pak"},
+		testFile{name: "chrome-linux/swiftshader/"},
+		testFile{name: "chrome-linux/lib/deep/nested/libfoo.so", body: "so"},
+	)
+	url := serveArchive(t, archive)
+
+	tests := []struct {
+		omit int
+		want map[string]string
+	}{
+		{omit: 0, want: map[string]string{
+			"README":                                 "top",
+			"chrome-linux/":                          "",
+			"chrome-linux/chrome":                    "binary",
+			"chrome-linux/locales/":                  "",
+			"chrome-linux/locales/en-US.pak":         "pak",
+			"chrome-linux/swiftshader/":              "",
+			"chrome-linux/lib/":                      "",
+			"chrome-linux/lib/deep/":                 "",
+			"chrome-linux/lib/deep/nested/":          "",
+			"chrome-linux/lib/deep/nested/libfoo.so": "so",
+		}},
+		{omit: 1, want: map[string]string{
+			"chrome":                    "binary",
+			"locales/":                  "",
+			"locales/en-US.pak":         "pak",
+			"swiftshader/":              "",
+			"lib/":                      "",
+			"lib/deep/":                 "",
+			"lib/deep/nested/":          "",
+			"lib/deep/nested/libfoo.so": "so",
+		}},
+	}
+	for _, test := range tests {
+		t.Run("omit"+strconv.Itoa(test.omit), func(t *testing.T) {
+			outPath := filepath.Join(t.TempDir(), "out")
+			result, err := newTestExtractor(url, outPath, WithOmitTopDirs(test.omit)).Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := readTree(t, outPath); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("extracted %v, want %v", got, test.want)
+			}
+			if result.FilesWritten != 4-test.omit {
+				t.Errorf("FilesWritten = %v, want %v", result.FilesWritten, 4-test.omit)
+			}
+			if result.DirsCreated != 3 {
+				t.Errorf("DirsCreated = %v, want 3", result.DirsCreated)
+			}
+			if runtime.GOOS == "windows" {
+				return
+			}
+			// Only chrome is executable
+			for name := range test.want {
+				fInfo, err := os.Stat(filepath.Join(outPath, filepath.FromSlash(name)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if executable := fInfo.Mode()&0100 != 0; !fInfo.IsDir() && executable != (path.Base(name) == "chrome") {
+					t.Errorf("mode of %s = %v", name, fInfo.Mode())
+				}
+			}
+		})
+	}
+}
+
+func TestRunRemoveOnFail(t *testing.T) {
+	var files []testFile
+	for i := 0; i < 8; i++ {
+		files = append(files, testFile{name: "chrome-linux/file" + strconv.Itoa(i), body: randomBody(64*1024 + i)})
+	}
+	archive := buildZip(t, zip.Store, files...)
+	url := serveTruncated(t, archive, len(archive)*3/4)
+
+	for _, removeOnFail := range []bool{false, true} {
+		t.Run("removeOnFail="+strconv.FormatBool(removeOnFail), func(t *testing.T) {
+			outPath := filepath.Join(t.TempDir(), "out")
+			_, err := newTestExtractor(url, outPath, WithRemoveOnFail(removeOnFail)).Run()
+			if err == nil {
+				t.Fatal("Run succeeded on a truncated archive")
+			}
+			if removeOnFail {
+				assertNotExist(t, outPath)
+			} else if len(readTree(t, outPath)) == 0 {
+				t.Error("no files were extracted before the connection was cut")
+			}
+		})
+	}
+}
+
+func TestRunPathTraversal(t *testing.T) {
+	archive := buildZip(t, zip.Deflate,
+		testFile{name: "chrome-linux/chrome", body: "binary"},
+		testFile{name: "../evil.txt", body: "evil"},
+	)
+	root := t.TempDir()
+	outPath := filepath.Join(root, "out")
+	_, err := newTestExtractor(serveArchive(t, archive), outPath, WithRemoveOnFail(true)).Run()
+	if err == nil {
+		t.Error("Run succeeded on an archive escaping the output path")
+	}
+	assertNotExist(t, filepath.Join(root, "evil.txt"))
+	assertNotExist(t, outPath)
+}
+
+func TestRunModTimes(t *testing.T) {
+	files := []testFile{
+		{name: "chrome-linux/"},
+		{name: "chrome-linux/locales/"},
+		{name: "chrome-linux/locales/en-US.pak", body: "pak"},
+		{name: "chrome-linux/chrome", body: "binary"},
+	}
+	archives := map[string][]byte{
+		"zip":    buildZip(t, zip.Deflate, files...),
+		"tar.gz": buildTarGz(t, files...),
+	}
+	for name, archive := range archives {
+		t.Run(name, func(t *testing.T) {
+			outPath := filepath.Join(t.TempDir(), "out")
+			_, err := newTestExtractor(serveArchive(t, archive), outPath).Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range files {
+				fInfo, err := os.Stat(filepath.Join(outPath, filepath.FromSlash(f.name)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !fInfo.ModTime().Equal(testTime) {
+					t.Errorf("modification time of %s = %v, want %v", f.name, fInfo.ModTime(), testTime)
+				}
+			}
+		})
+	}
+}
+
+func TestRunDirsCreated(t *testing.T) {
+	files := []testFile{
+		{name: "chrome-linux/"},
+		{name: "chrome-linux/locales/"},
+		{name: "chrome-linux/locales/en-US.pak", body: "pak"},
+		{name: "chrome-linux/swiftshader/"},
+	}
+	archives := map[string][]byte{
+		"zip":    buildZip(t, zip.Deflate, files...),
+		"tar.gz": buildTarGz(t, files...),
+	}
+	for name, archive := range archives {
+		t.Run(name, func(t *testing.T) {
+			result, err := newTestExtractor(serveArchive(t, archive), filepath.Join(t.TempDir(), "out")).Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.DirsCreated != 3 || result.FilesWritten != 1 {
+				t.Errorf("DirsCreated = %v, FilesWritten = %v, want 3 and 1", result.DirsCreated, result.FilesWritten)
+			}
+		})
+	}
+}
+
+func TestShortenPath(t *testing.T) {
+	tests := []struct {
+		name string
+		omit int
+		want string
+	}{
+		{name: "chrome-linux/chrome", omit: 0, want: "chrome-linux/chrome"},
+		{name: "chrome-linux/chrome", omit: 1, want: "chrome"},
+		{name: "chrome-linux/locales/en-US.pak", omit: 1, want: "locales/en-US.pak"},
+		{name: "chrome-linux/locales/en-US.pak", omit: 2, want: "en-US.pak"},
+		{name: "chrome-linux/", omit: 0, want: "chrome-linux/"},
+		{name: "chrome-linux/", omit: 1, want: ""},
+		{name: "chrome-linux/locales/", omit: 1, want: "locales/"},
+		{name: "chrome-linux/locales/", omit: 2, want: ""},
+		{name: "chrome", omit: 1, want: ""},
+		{name: "chrome-linux/chrome", omit: 2, want: ""},
+		{name: "chrome-linux/chrome", omit: 5, want: ""},
+		{name: "/chrome-linux/chrome", omit: 1, want: "chrome"},
+		{name: `chrome-win\chrome.exe`, omit: 1, want: "chrome.exe"},
+		{name: `chrome-win\chrome.exe`, omit: 0, want: "chrome-win/chrome.exe"},
+	}
+	for _, test := range tests {
+		d := NewDownloadExtractor("", "")
+		d.OmitTopDirs(test.omit)
+		if got := d.shortenPath(test.name); got != test.want {
+			t.Errorf("shortenPath(%q) with %v omitted dirs = %q, want %q", test.name, test.omit, got, test.want)
+		}
+	}
+}
+
+func TestRunEmptyArchive(t *testing.T) {
+	archives := map[string][]byte{
+		"no entries":       buildZip(t, zip.Deflate),
+		"directories only": buildZip(t, zip.Deflate, testFile{name: "chrome-linux/"}, testFile{name: "chrome-linux/locales/"}),
+	}
+	for name, archive := range archives {
+		t.Run(name, func(t *testing.T) {
+			outPath := filepath.Join(t.TempDir(), "out")
+			_, err := newTestExtractor(serveArchive(t, archive), outPath, WithRemoveOnFail(true)).Run()
+			if !errors.Is(err, ErrEmptyArchive) {
+				t.Errorf("Run returned %v, want ErrEmptyArchive", err)
+			}
+			assertNotExist(t, outPath)
+		})
+	}
+}
+
+func TestRunOmitTopDirsTraversal(t *testing.T) {
+	names := []string{"chrome-linux/../evil", "top/../../evil", `chrome-linux\..\evil`}
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			archive := buildZip(t, zip.Deflate,
+				testFile{name: "chrome-linux/chrome", body: "binary"},
+				testFile{name: name, body: "evil"},
+			)
+			root := t.TempDir()
+			outPath := filepath.Join(root, "out", "chromium")
+			_, err := newTestExtractor(serveArchive(t, archive), outPath, WithOmitTopDirs(1), WithRemoveOnFail(true)).Run()
+			if err == nil {
+				t.Error("Run succeeded on an archive escaping the output path")
+			}
+			assertNotExist(t, filepath.Join(root, "evil"))
+			assertNotExist(t, filepath.Join(root, "out", "evil"))
+			assertNotExist(t, outPath)
+		})
+	}
+}
+
+func TestRunTruncated(t *testing.T) {
+	archive := buildZip(t, zip.Store, testFile{name: "chrome-linux/chrome", body: randomBody(256 * 1024)})
+	// Every response of the ranged server, including those for parts of the archive, ends after 1 KiB
+	ranged := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(&cutWriter{ResponseWriter: w, n: 1024}, r, "", testTime, bytes.NewReader(archive))
+	}))
+	defer ranged.Close()
+
+	tests := []struct {
+		name string
+		url  string
+		opt  Option
+	}{
+		{name: "streamed", url: serveTruncated(t, archive, len(archive)/2), opt: WithResumable(false)},
+		{name: "resumable", url: serveTruncated(t, archive, len(archive)/2), opt: WithResumable(true)},
+		{name: "parallel", url: ranged.URL, opt: WithParallelConnections(4)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			outPath := filepath.Join(t.TempDir(), "out")
+			_, err := newTestExtractor(test.url, outPath, test.opt, WithRemoveOnFail(true)).Run()
+			if !errors.Is(err, ErrTruncated) {
+				t.Errorf("Run returned %v, want ErrTruncated", err)
+			}
+			assertNotExist(t, outPath)
+		})
+	}
+}
+
+func TestRunEmptyFile(t *testing.T) {
+	archive := buildZip(t, zip.Store,
+		testFile{name: "chrome-linux/empty", mode: 0755},
+		testFile{name: "chrome-linux/chrome", body: "binary", mode: 0755},
+	)
+	outPath := filepath.Join(t.TempDir(), "out")
+	var logged bytes.Buffer
+	result, err := newTestExtractor(serveArchive(t, archive), outPath, WithVerbose(true), WithLogger(log.New(&logged, "", 0))).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logged.String(), "Created empty file") || strings.Contains(logged.String(), "Wrote 0 bytes") {
+		t.Errorf("verbose output does not list the empty file as created:\n%s", logged.String())
+	}
+	if result.FilesWritten != 2 {
+		t.Errorf("FilesWritten = %v, want 2", result.FilesWritten)
+	}
+	fInfo, err := os.Stat(filepath.Join(outPath, "chrome-linux", "empty"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fInfo.Size() != 0 || !fInfo.Mode().IsRegular() {
+		t.Errorf("extracted %v with %v bytes, want an empty regular file", fInfo.Mode(), fInfo.Size())
+	}
+	if want := 0755 &^ processUmask(); preserveModes && fInfo.Mode().Perm() != want {
+		t.Errorf("mode = %v, want %v", fInfo.Mode().Perm(), want)
+	}
+}
+
+func TestRunSymlinkEscape(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links require privileges on Windows")
+	}
+	tests := []struct {
+		name  string
+		files []testFile
+	}{
+		{name: "chained links", files: []testFile{
+			{name: "a/b", link: ".."},
+			{name: "a/b/c", link: ".."},
+			{name: "a/b/c/evil", body: "evil"},
+		}},
+		{name: "link leaving a link", files: []testFile{
+			{name: "a/b", link: ".."},
+			{name: "x", link: "a/b/.."},
+			{name: "x/evil", body: "evil"},
+		}},
+		{name: "partial file link", files: []testFile{
+			{name: "a/b", link: ".."},
+			{name: "evil.partial", link: "a/b/../evil"},
+			{name: "evil", body: "evil"},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			outPath := filepath.Join(root, "out", "chromium")
+			url := serveArchive(t, buildTarGz(t, test.files...))
+			newTestExtractor(url, outPath).Run()
+			for _, evil := range []string{filepath.Join(root, "evil"), filepath.Join(root, "out", "evil")} {
+				assertNotExist(t, evil)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package downloadextract
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// contentEncoding returns the normalized Content-Encoding of resp, which is empty for unencoded responses.
+// Responses decompressed transparently by the http client carry no Content-Encoding anymore.
+func contentEncoding(resp *http.Response) string {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding == "identity" {
+		return ""
+	}
+	return encoding
+}
+
+// decodeContent wraps body, the body of resp, in a decompressor according to the Content-Encoding of resp.
+// Some mirrors compress archives on top, whether they were asked to or not, which would otherwise break the extraction.
+func decodeContent(resp *http.Response, body io.Reader) (io.Reader, error) {
+	switch contentEncoding(resp) {
+	case "":
+		return body, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "deflate":
+		// Servers disagree on whether deflate means zlib wrapped or raw deflate data, so the zlib header is checked
+		bR := bufio.NewReader(body)
+		header, err := bR.Peek(2)
+		if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			return zlib.NewReader(bR)
+		}
+		return flate.NewReader(bR), nil
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding \"%s\"", resp.Header.Get("Content-Encoding"))
+	}
+}
@@ -0,0 +1,53 @@
+package downloadextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunContentEncoding(t *testing.T) {
+	archive := buildZip(t, zip.Deflate, testFile{name: "chrome-linux/chrome", body: "binary"})
+	md5Sum := md5.Sum(archive)
+
+	tests := []struct {
+		encoding string
+		writer   func(w io.Writer) io.WriteCloser
+	}{
+		{encoding: "gzip", writer: func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }},
+		{encoding: "deflate", writer: func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }},
+		{encoding: "deflate", writer: func(w io.Writer) io.WriteCloser {
+			fW, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fW
+		}},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		w := test.writer(&buf)
+		w.Write(archive)
+		w.Close()
+		encoded := buf.Bytes()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", test.encoding)
+			w.Write(encoded)
+		}))
+		outPath := filepath.Join(t.TempDir(), "out")
+		result, err := newTestExtractor(srv.URL, outPath, WithExpectedChecksum("md5", hex.EncodeToString(md5Sum[:]))).Run()
+		srv.Close()
+		if err != nil {
+			t.Errorf("Run with Content-Encoding %s returned %v", test.encoding, err)
+		} else if result.FilesWritten != 1 {
+			t.Errorf("FilesWritten with Content-Encoding %s = %v, want 1", test.encoding, result.FilesWritten)
+		}
+	}
+}
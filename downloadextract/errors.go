@@ -0,0 +1,89 @@
+package downloadextract
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// TransferError is returned if the archive could not be received completely, like when the connection dropped or the server sent fewer bytes than it announced.
+// Unlike for a FormatError, running again may succeed.
+type TransferError struct {
+	Err error
+}
+
+func (e *TransferError) Error() string {
+	return "download broke off: " + e.Err.Error()
+}
+
+func (e *TransferError) Unwrap() error {
+	return e.Err
+}
+
+// FormatError is returned if the archive is malformed, like a corrupt zip archive or one of an unknown format.
+// Running again will fail the same way, unless the archive is replaced.
+type FormatError struct {
+	Err error
+}
+
+func (e *FormatError) Error() string {
+	return "malformed archive: " + e.Err.Error()
+}
+
+func (e *FormatError) Unwrap() error {
+	return e.Err
+}
+
+// transferError wraps err, which occurred while receiving the archive, in a TransferError.
+// Errors caused by ctx being done and TransferErrors are returned as they are.
+func transferError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	var transferErr *TransferError
+	if errors.As(err, &transferErr) {
+		return err
+	}
+	return &TransferError{Err: err}
+}
+
+// formatError wraps err, which occurred while reading the archive, in a FormatError.
+// Errors not caused by the content of the archive, like a broken download or a closed pipe, are returned as they are.
+func formatError(err error) error {
+	var transferErr *TransferError
+	if err == nil || err == io.EOF || errors.As(err, &transferErr) || errors.Is(err, io.ErrClosedPipe) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return &FormatError{Err: err}
+}
+
+// formatReader wraps the errors of reading the content of archive entries from r with formatError.
+type formatReader struct {
+	r io.Reader
+}
+
+func (f formatReader) Read(b []byte) (int, error) {
+	n, err := f.r.Read(b)
+	return n, formatError(err)
+}
+
+// fetchError returns the error of a streamed run, given the error err of reading the archive and the error fetchErr of receiving it.
+// An archive whose download failed looks malformed to the reader, so the error of the download is returned instead.
+func fetchError(err error, fetchErr error) error {
+	if err == nil || fetchErr == nil || errors.Is(fetchErr, io.ErrClosedPipe) || errors.Is(fetchErr, context.Canceled) {
+		return err
+	}
+	if errors.Is(fetchErr, ErrNotModified) {
+		return fetchErr
+	}
+	var extractErr *ExtractError
+	if errors.As(err, &extractErr) {
+		extractErr.Err = fetchErr
+		return extractErr
+	}
+	return fetchErr
+}
@@ -0,0 +1,32 @@
+package downloadextract
+
+import (
+	"archive/zip"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunErrorTypes(t *testing.T) {
+	archive := buildZip(t, zip.Deflate, testFile{name: "chrome-linux/chrome", body: strings.Repeat("chromium ", 64*1024)})
+	corrupt := append([]byte(nil), archive...)
+	for i := 60; i < 200; i++ {
+		corrupt[i] = 0xff
+	}
+
+	t.Run("cut connection", func(t *testing.T) {
+		_, err := newTestExtractor(serveTruncated(t, archive, len(archive)/2), filepath.Join(t.TempDir(), "out")).Run()
+		var transferErr *TransferError
+		if !errors.As(err, &transferErr) {
+			t.Errorf("Run returned %v, want a TransferError", err)
+		}
+	})
+	t.Run("corrupt archive", func(t *testing.T) {
+		_, err := newTestExtractor(serveArchive(t, corrupt), filepath.Join(t.TempDir(), "out")).Run()
+		var formatErr *FormatError
+		if !errors.As(err, &formatErr) {
+			t.Errorf("Run returned %v, want a FormatError", err)
+		}
+	})
+}
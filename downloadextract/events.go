@@ -0,0 +1,76 @@
+package downloadextract
+
+// EventType distinguishes the kinds of events of a run.
+type EventType int
+
+const (
+	// EventStart is sent when a run begins.
+	EventStart EventType = iota
+	// EventProgress is sent periodically while the archive is downloaded, with BytesDownloaded and TotalBytes set.
+	EventProgress
+	// EventFileWritten is sent for every extracted file, with Path and Size set.
+	EventFileWritten
+	// EventDone is sent when a run succeeded, with Result set.
+	EventDone
+	// EventError is sent when a run failed, with Err and Result set.
+	EventError
+	// EventExtractProgress is sent whenever a file has been extracted, with FilesExtracted, TotalFiles and BytesWritten set.
+	EventExtractProgress
+)
+
+// Event describes something which happened during a run. The fields which are set depend on Type.
+type Event struct {
+	Type EventType
+	// BytesDownloaded and TotalBytes are the arguments of a ProgressFunc.
+	BytesDownloaded int64
+	TotalBytes      int64
+	// FilesExtracted, TotalFiles and BytesWritten are the arguments of an ExtractProgressFunc.
+	FilesExtracted int
+	TotalFiles     int
+	BytesWritten   int64
+	// Path is the path of a written file and Size its size.
+	Path string
+	Size int64
+	// Result are the statistics of a finished run.
+	Result Result
+	// Err is the error a run failed with.
+	Err error
+}
+
+// WithEventChannel is the Option equivalent of SetEventChannel.
+func WithEventChannel(ch chan<- Event) Option {
+	return func(d *DownloadExtractor) {
+		d.events = ch
+	}
+}
+
+// SetEventChannel enables sending events about the progress of runs to ch, e.g. for user interfaces.
+// Sending never blocks the download or extraction, so events are dropped while ch is full.
+// Use a buffered channel to avoid losing events, the outcome of a run is returned by Run regardless. The channel is not closed by d.
+func (d *DownloadExtractor) SetEventChannel(ch chan<- Event) {
+	WithEventChannel(ch)(d)
+}
+
+// emit sends e to the event channel, if any, and drops it if the channel is full.
+func (d *DownloadExtractor) emit(e Event) {
+	if d.events == nil {
+		return
+	}
+	select {
+	case d.events <- e:
+	default:
+	}
+}
+
+// progressFunc returns the ProgressFunc reporting to the progress callback and the event channel, or nil if there are neither.
+func (d *DownloadExtractor) progressFunc() ProgressFunc {
+	if d.events == nil {
+		return d.progress
+	}
+	return func(bytesDownloaded, totalBytes int64) {
+		if d.progress != nil {
+			d.progress(bytesDownloaded, totalBytes)
+		}
+		d.emit(Event{Type: EventProgress, BytesDownloaded: bytesDownloaded, TotalBytes: totalBytes})
+	}
+}
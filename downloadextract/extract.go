@@ -0,0 +1,56 @@
+package downloadextract
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// Extract extracts the archive read from r to a folder at outPath, without downloading anything.
+// It is configured by opts like a DownloadExtractor created by New, except for the options concerning the download, which have no effect.
+// The fields of the Result which describe the download, like ContentLength, are left at their defaults.
+func Extract(r io.Reader, outPath string, opts ...Option) (Result, error) {
+	return New("", outPath, opts...).ExtractContext(context.Background(), r)
+}
+
+// ExtractContext extracts the archive read from r to the output path of d instead of downloading it from the URL of d.
+// It aborts as soon as ctx is done.
+func (d *DownloadExtractor) ExtractContext(ctx context.Context, r io.Reader) (Result, error) {
+	return d.run(ctx, func(ctx context.Context, result *Result) error {
+		return d.extract(ctx, r, result)
+	})
+}
+
+// ExtractFile extracts the archive file at path to a folder at outPath, like Extract.
+func ExtractFile(path string, outPath string, opts ...Option) (Result, error) {
+	return New("", outPath, opts...).ExtractFileContext(context.Background(), path)
+}
+
+// ExtractFileContext extracts the archive file at path to the output path of d instead of downloading it from the URL of d.
+// Before anything is written, the file is checked to be an archive of a supported format and verified against the checksum of SetExpectedChecksum, if any.
+// It aborts as soon as ctx is done.
+func (d *DownloadExtractor) ExtractFileContext(ctx context.Context, path string) (Result, error) {
+	return d.run(ctx, func(ctx context.Context, result *Result) error {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if d.checksum != "" {
+			err = d.verifyFile(path)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = newArchiveReader(f, d.format)
+		if err != nil {
+			return formatError(err)
+		}
+		_, err = f.Seek(0, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		return d.extract(ctx, f, result)
+	})
+}
@@ -0,0 +1,91 @@
+package downloadextract
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+)
+
+// ExtractProgressFunc is called with the number of files extracted so far, the total number of files to extract and the number of bytes written.
+// totalFiles is -1 if the number is not known before the whole archive is read.
+type ExtractProgressFunc func(filesExtracted, totalFiles int, bytesWritten int64)
+
+// WithExtractProgress is the Option equivalent of SetExtractProgressCallback.
+func WithExtractProgress(callback ExtractProgressFunc) Option {
+	return func(d *DownloadExtractor) {
+		d.extractProgress = callback
+	}
+}
+
+// SetExtractProgressCallback registers a function which is called whenever a file has been extracted, counting skipped unchanged files as well.
+// Unlike with SetProgressCallback, this reports how far writing the files got, which lags behind the download on slow disks.
+// The total number of files is only known for zip archives extracted from a file, like with SetBufferToDisk, whose central directory is read upfront.
+func (d *DownloadExtractor) SetExtractProgressCallback(callback ExtractProgressFunc) {
+	WithExtractProgress(callback)(d)
+}
+
+// extractProgressFunc returns the ExtractProgressFunc reporting to the extraction progress callback and the event channel, or nil if there are neither.
+func (d *DownloadExtractor) extractProgressFunc() ExtractProgressFunc {
+	if d.events == nil {
+		return d.extractProgress
+	}
+	return func(filesExtracted, totalFiles int, bytesWritten int64) {
+		if d.extractProgress != nil {
+			d.extractProgress(filesExtracted, totalFiles, bytesWritten)
+		}
+		d.emit(Event{Type: EventExtractProgress, FilesExtracted: filesExtracted, TotalFiles: totalFiles, BytesWritten: bytesWritten})
+	}
+}
+
+// extractProgress reports the files extracted according to a Result whenever their number changed.
+type extractProgress struct {
+	callback ExtractProgressFunc
+	total    int
+	reported int
+}
+
+// newExtractProgress creates an extractProgress for the archive read from r, or returns nil if there is nothing to report to.
+func (d *DownloadExtractor) newExtractProgress(r io.Reader) *extractProgress {
+	callback := d.extractProgressFunc()
+	if callback == nil {
+		return nil
+	}
+	return &extractProgress{callback: callback, total: d.totalFiles(r)}
+}
+
+func (p *extractProgress) update(result *Result) {
+	if p == nil {
+		return
+	}
+	done := result.FilesWritten + result.FilesSkipped
+	if done == p.reported {
+		return
+	}
+	p.reported = done
+	p.callback(done, p.total, result.TotalBytes)
+}
+
+// totalFiles returns the number of files which the archive read from r contains below the omitted top directories and passing the filters, or -1 if it cannot be known before the whole archive is read.
+// Only zip archives read from a file reveal it upfront, in their central directory.
+func (d *DownloadExtractor) totalFiles(r io.Reader) int {
+	f, ok := r.(*os.File)
+	if !ok || d.format == TarGz {
+		return -1
+	}
+	fInfo, err := f.Stat()
+	if err != nil {
+		return -1
+	}
+	zR, err := zip.NewReader(f, fInfo.Size())
+	if err != nil {
+		return -1
+	}
+	n := 0
+	for _, zf := range zR.File {
+		if zf.FileInfo().IsDir() || d.shortenPath(zf.Name) == "" || !d.included(zf.Name) {
+			continue
+		}
+		n++
+	}
+	return n
+}
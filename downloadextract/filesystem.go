@@ -0,0 +1,81 @@
+package downloadextract
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Filesystem is the destination extracted files are written to.
+// Paths are given in the form of the operating system, below the output path of the DownloadExtractor.
+type Filesystem interface {
+	MkdirAll(path string, perm os.FileMode) error
+	// OpenFile opens a file for writing, flag is a combination of os.O_WRONLY, os.O_CREATE and os.O_TRUNC.
+	OpenFile(name string, flag int, perm os.FileMode) (io.WriteCloser, error)
+	ReadFile(name string) ([]byte, error)
+	Rename(oldpath string, newpath string) error
+	Remove(name string) error
+	RemoveAll(path string) error
+	Symlink(oldname string, newname string) error
+	Chmod(name string, mode os.FileMode) error
+	Chtimes(name string, atime time.Time, mtime time.Time) error
+	Lstat(name string) (os.FileInfo, error)
+}
+
+// WithFilesystem is the Option equivalent of SetFilesystem.
+func WithFilesystem(fs Filesystem) Option {
+	return func(d *DownloadExtractor) {
+		d.fs = fs
+	}
+}
+
+// SetFilesystem sets the destination of extracted files, which is the file system of the operating system by default.
+// This allows extracting into an in-memory or remote storage, e.g. for tests.
+// Files which are not part of the extracted tree, like the downloaded archive of resumable mode or archive-only mode, are still stored by the operating system.
+func (d *DownloadExtractor) SetFilesystem(fs Filesystem) {
+	WithFilesystem(fs)(d)
+}
+
+// osFilesystem is the Filesystem of the operating system.
+type osFilesystem struct{}
+
+func (osFilesystem) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (osFilesystem) OpenFile(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (osFilesystem) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+
+func (osFilesystem) Rename(oldpath string, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (osFilesystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (osFilesystem) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+func (osFilesystem) Symlink(oldname string, newname string) error {
+	return os.Symlink(oldname, newname)
+}
+
+func (osFilesystem) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+func (osFilesystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+func (osFilesystem) Lstat(name string) (os.FileInfo, error) {
+	return os.Lstat(name)
+}
@@ -0,0 +1,75 @@
+package downloadextract
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// WithInclude is the Option equivalent of SetInclude.
+func WithInclude(patterns []string) Option {
+	return func(d *DownloadExtractor) {
+		d.include = patterns
+	}
+}
+
+// SetInclude restricts the extraction to archive entries matching at least one of the glob patterns, in the syntax of path.Match.
+// Patterns are matched against the slash separated entry path after the top directories have been omitted, see OmitTopDirs, so with OmitTopDirs(1) "chrome" matches the entry "chrome-linux/chrome".
+// An entry also matches if one of its parent directories does, so "locales" includes all files below it.
+func (d *DownloadExtractor) SetInclude(patterns []string) {
+	WithInclude(patterns)(d)
+}
+
+// WithExclude is the Option equivalent of SetExclude.
+func WithExclude(patterns []string) Option {
+	return func(d *DownloadExtractor) {
+		d.exclude = patterns
+	}
+}
+
+// SetExclude skips archive entries matching any of the glob patterns, which are matched like the patterns of SetInclude.
+// Exclusion takes precedence over inclusion.
+func (d *DownloadExtractor) SetExclude(patterns []string) {
+	WithExclude(patterns)(d)
+}
+
+// checkPatterns returns an error if any include or exclude pattern is malformed.
+func (d *DownloadExtractor) checkPatterns() error {
+	for _, patterns := range [][]string{d.include, d.exclude} {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid pattern \"%s\": %w", p, err)
+			}
+		}
+	}
+	return nil
+}
+
+// included reports whether the archive entry called name is to be extracted according to the include and exclude patterns.
+func (d *DownloadExtractor) included(name string) bool {
+	if len(d.include) == 0 && len(d.exclude) == 0 {
+		return true
+	}
+	rel := path.Clean(d.shortenPath(name))
+	if len(d.include) > 0 && !matchesAny(d.include, rel) {
+		return false
+	}
+	return !matchesAny(d.exclude, rel)
+}
+
+// matchesAny reports whether any of patterns matches the slash separated path rel or one of its parent directories.
+func matchesAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		for prefix := rel; ; {
+			if ok, _ := path.Match(p, prefix); ok {
+				return true
+			}
+			i := strings.LastIndex(prefix, "/")
+			if i < 0 {
+				break
+			}
+			prefix = prefix[:i]
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package downloadextract
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CollisionPolicy decides what happens if files of different directories share a name when they are flattened.
+type CollisionPolicy int
+
+const (
+	// CollisionError fails the run.
+	CollisionError CollisionPolicy = iota
+	// CollisionOverwrite keeps the file which comes last in the archive.
+	CollisionOverwrite
+	// CollisionRename extracts later files with a number appended to the name, like "name-2.ext".
+	CollisionRename
+)
+
+// WithFlatten is the Option equivalent of SetFlatten.
+func WithFlatten(b bool) Option {
+	return func(d *DownloadExtractor) {
+		d.flatten = b
+	}
+}
+
+// SetFlatten enables, when set to true, extracting all files directly into the output path rather than into subfolders.
+// Only the base name of an entry is used as its file name, directory entries are skipped.
+// OmitTopDirs still applies, so files located within the omitted top folders themselves are not extracted.
+// Name collisions are handled according to SetCollisionPolicy.
+// Filters of SetInclude and SetExclude match the paths the files would have without flattening.
+func (d *DownloadExtractor) SetFlatten(b bool) {
+	WithFlatten(b)(d)
+}
+
+// WithCollisionPolicy is the Option equivalent of SetCollisionPolicy.
+func WithCollisionPolicy(p CollisionPolicy) Option {
+	return func(d *DownloadExtractor) {
+		d.collisionPolicy = p
+	}
+}
+
+// SetCollisionPolicy sets how files sharing a name are handled when they are flattened, see SetFlatten.
+// By default, the run fails with CollisionError.
+func (d *DownloadExtractor) SetCollisionPolicy(p CollisionPolicy) {
+	WithCollisionPolicy(p)(d)
+}
+
+// flatPath returns the path the archive entry called name with the shortened path is extracted to when flattening.
+// Every entry keeps its path for the whole run, so metadata applied later ends up at the same file.
+func (d *DownloadExtractor) flatPath(name string, shortened string) (string, error) {
+	if fPath, ok := d.flatPaths[name]; ok {
+		return fPath, nil
+	}
+	base := path.Base(shortened)
+	fPath := filepath.Join(d.outPath, base)
+	if d.flatUsed[fPath] {
+		switch d.collisionPolicy {
+		case CollisionError:
+			return "", fmt.Errorf("file name \"%s\" is not unique among the flattened files", base)
+		case CollisionRename:
+			ext := path.Ext(base)
+			for n := 2; d.flatUsed[fPath]; n++ {
+				fPath = filepath.Join(d.outPath, strings.TrimSuffix(base, ext)+"-"+strconv.Itoa(n)+ext)
+			}
+		}
+	}
+	d.flatUsed[fPath] = true
+	d.flatPaths[name] = fPath
+	return fPath, d.checkContained(fPath)
+}
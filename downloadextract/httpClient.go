@@ -0,0 +1,38 @@
+package downloadextract
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// DialTimeout is the maximum amount of time the http client of NewHTTPClient waits for a connection to be established.
+const DialTimeout = 30 * time.Second
+
+// NewHTTPClient creates the http client used by a DownloadExtractor, unless another one is set via SetHTTPClient.
+// Establishing a connection times out after DialTimeout, but there is no overall timeout, as archives may be large and take a while to download.
+// Proxies are configured by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables, the Proxy of the returned client's *http.Transport may be replaced to override them.
+func NewHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = DialTimeout
+	transport.ResponseHeaderTimeout = DialTimeout
+
+	return &http.Client{Transport: transport}
+}
+
+// WithUserAgent is the Option equivalent of SetUserAgent.
+func WithUserAgent(ua string) Option {
+	return func(d *DownloadExtractor) {
+		d.userAgent = ua
+	}
+}
+
+// SetUserAgent sets the User-Agent header of all requests sent by d.
+// An empty string keeps the default of the http client, which is the default.
+func (d *DownloadExtractor) SetUserAgent(ua string) {
+	WithUserAgent(ua)(d)
+}
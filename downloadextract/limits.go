@@ -0,0 +1,79 @@
+package downloadextract
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// defaultMaxTotalBytes is the default limit of the extracted size, far above the size of any Chromium build.
+	defaultMaxTotalBytes = 16 * 1024 * 1024 * 1024
+	// defaultMaxFiles is the default limit of the number of extracted files, far above the number of files of any Chromium build.
+	defaultMaxFiles = 200000
+)
+
+// ErrLimitExceeded is wrapped by the error returned if an archive exceeds the limits of SetMaxTotalBytes or SetMaxFiles.
+var ErrLimitExceeded = errors.New("archive exceeds the extraction limits")
+
+// WithMaxTotalBytes is the Option equivalent of SetMaxTotalBytes.
+func WithMaxTotalBytes(n int64) Option {
+	return func(d *DownloadExtractor) {
+		d.maxTotalBytes = n
+	}
+}
+
+// SetMaxTotalBytes aborts the run once the extracted files exceed n bytes in total, which protects against decompression bombs.
+// The default is 16 GiB, a value of 0 means unlimited.
+func (d *DownloadExtractor) SetMaxTotalBytes(n int64) {
+	WithMaxTotalBytes(n)(d)
+}
+
+// WithMaxFiles is the Option equivalent of SetMaxFiles.
+func WithMaxFiles(n int) Option {
+	return func(d *DownloadExtractor) {
+		d.maxFiles = n
+	}
+}
+
+// SetMaxFiles aborts the run once the archive contains more than n files.
+// The default is 200000, a value of 0 means unlimited.
+func (d *DownloadExtractor) SetMaxFiles(n int) {
+	WithMaxFiles(n)(d)
+}
+
+// checkMaxFiles returns an error if extracting another file after filesWritten ones exceeds the limit of files.
+func (d *DownloadExtractor) checkMaxFiles(filesWritten int) error {
+	if d.maxFiles > 0 && filesWritten >= d.maxFiles {
+		return fmt.Errorf("%w: more than %v files", ErrLimitExceeded, d.maxFiles)
+	}
+	return nil
+}
+
+// limitContent returns a reader of the content of an archive entry from r, which fails once the extracted size exceeds the limit of bytes.
+func (d *DownloadExtractor) limitContent(r io.Reader, totalBytes int64) io.Reader {
+	if d.maxTotalBytes <= 0 {
+		return r
+	}
+	return &capReader{r: r, remaining: d.maxTotalBytes - totalBytes, max: d.maxTotalBytes}
+}
+
+// capReader reads from r and fails as soon as more than remaining bytes are read.
+// Unlike io.LimitReader, exceeding the limit is an error rather than the end of the data.
+type capReader struct {
+	r         io.Reader
+	remaining int64
+	max       int64
+}
+
+func (c *capReader) Read(b []byte) (int, error) {
+	if int64(len(b)) > c.remaining+1 {
+		b = b[:c.remaining+1]
+	}
+	n, err := c.r.Read(b)
+	if int64(n) > c.remaining {
+		return int(c.remaining), fmt.Errorf("%w: more than %v bytes", ErrLimitExceeded, c.max)
+	}
+	c.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,43 @@
+package downloadextract
+
+import (
+	"archive/zip"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRunLimits(t *testing.T) {
+	var files []testFile
+	for i := 0; i < 5; i++ {
+		files = append(files, testFile{name: "chrome-linux/file" + strconv.Itoa(i), body: "content"})
+	}
+	tests := []struct {
+		name    string
+		archive []byte
+		opt     Option
+	}{
+		{
+			name:    "total bytes",
+			archive: buildZip(t, zip.Deflate, testFile{name: "chrome-linux/bomb", body: strings.Repeat("0", 1024*1024)}),
+			opt:     WithMaxTotalBytes(64 * 1024),
+		},
+		{
+			name:    "files",
+			archive: buildZip(t, zip.Deflate, files...),
+			opt:     WithMaxFiles(3),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			outPath := filepath.Join(t.TempDir(), "out")
+			_, err := newTestExtractor(serveArchive(t, test.archive), outPath, test.opt, WithRemoveOnFail(true)).Run()
+			if !errors.Is(err, ErrLimitExceeded) {
+				t.Errorf("Run returned %v, want ErrLimitExceeded", err)
+			}
+			assertNotExist(t, outPath)
+		})
+	}
+}
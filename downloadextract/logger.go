@@ -0,0 +1,29 @@
+package downloadextract
+
+import "fmt"
+
+// Logger receives the progress and status messages of a DownloadExtractor.
+// A *log.Logger satisfies this interface.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// stdoutLogger prints messages to stdout as they are.
+type stdoutLogger struct{}
+
+func (stdoutLogger) Printf(format string, v ...interface{}) {
+	fmt.Printf(format, v...)
+}
+
+// WithLogger is the Option equivalent of SetLogger.
+func WithLogger(l Logger) Option {
+	return func(d *DownloadExtractor) {
+		d.logger = l
+	}
+}
+
+// SetLogger sets the Logger all messages are written to.
+// By default, messages are printed to stdout.
+func (d *DownloadExtractor) SetLogger(l Logger) {
+	WithLogger(l)(d)
+}
@@ -0,0 +1,62 @@
+package downloadextract
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// WithManifest is the Option equivalent of SetManifest.
+func WithManifest(w io.Writer) Option {
+	return func(d *DownloadExtractor) {
+		d.manifestOut = w
+	}
+}
+
+// SetManifest enables writing a manifest of all extracted regular files to w after a successful extraction.
+// The manifest is in the format of sha256sum, one line with the hex encoded SHA-256 digest and the slash separated path relative to the output path per file, sorted by path.
+// Digests are computed from the data while it is written, so files are not read a second time.
+func (d *DownloadExtractor) SetManifest(w io.Writer) {
+	WithManifest(w)(d)
+}
+
+// manifest collects the digests of extracted files, possibly from several writing goroutines.
+type manifest struct {
+	mu   sync.Mutex
+	sums map[string]string
+}
+
+func newManifest() *manifest {
+	return &manifest{sums: map[string]string{}}
+}
+
+// add records the hex encoded digest sum of the file at the relative path rel.
+func (m *manifest) add(rel string, sum string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sums[rel] = sum
+}
+
+// write writes the manifest to w, leaving out files below outPath in fs which are no regular files anymore, like zip entries turned into symbolic links.
+func (m *manifest) write(w io.Writer, fs Filesystem, outPath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	paths := make([]string, 0, len(m.sums))
+	for rel := range m.sums {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	for _, rel := range paths {
+		fInfo, err := fs.Lstat(filepath.Join(outPath, filepath.FromSlash(rel)))
+		if err != nil || !fInfo.Mode().IsRegular() {
+			continue
+		}
+		_, err = fmt.Fprintf(w, "%s  %s\n", m.sums[rel], rel)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
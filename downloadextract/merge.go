@@ -0,0 +1,52 @@
+package downloadextract
+
+import "time"
+
+// WithMergeMode is the Option equivalent of SetMergeMode.
+func WithMergeMode(b bool) Option {
+	return func(d *DownloadExtractor) {
+		d.merge = b
+	}
+}
+
+// SetMergeMode enables, when set to true, extracting into an already populated output path.
+// Files of the archive overwrite existing files of the same name, all other files are left untouched.
+// Contrary to extracting into a fresh directory which is renamed into place afterwards, merging is not atomic:
+// a failed or interrupted run leaves a mix of old and new files behind, and RemoveOnFail is ignored, so files which were not part of the archive are never deleted.
+// Every single file is still replaced atomically though, so it either has its old or its new contents.
+func (d *DownloadExtractor) SetMergeMode(b bool) {
+	WithMergeMode(b)(d)
+}
+
+// ExtractInto sets the output path to path and enables merge mode, see SetMergeMode.
+func (d *DownloadExtractor) ExtractInto(path string) {
+	d.outPath = path
+	d.SetMergeMode(true)
+}
+
+// WithSkipUnchanged is the Option equivalent of SetSkipUnchanged.
+func WithSkipUnchanged(b bool) Option {
+	return func(d *DownloadExtractor) {
+		d.skipUnchanged = b
+	}
+}
+
+// SetSkipUnchanged enables, when set to true, skipping files in merge mode which already exist with the size and modification time of the archive entry.
+// Skipped files are counted in Result.FilesSkipped instead of Result.FilesWritten and are not part of the manifest.
+// Entries whose size is unknown in advance, like those of zip archives written with data descriptors, are always written.
+func (d *DownloadExtractor) SetSkipUnchanged(b bool) {
+	WithSkipUnchanged(b)(d)
+}
+
+// unchanged reports whether the regular file entry fHdr can be skipped, as the file at fPath already matches it.
+func (d *DownloadExtractor) unchanged(fPath string, fHdr *entry) bool {
+	if !d.merge || !d.skipUnchanged || !fHdr.sizeKnown {
+		return false
+	}
+	fInfo, err := d.fs.Lstat(fPath)
+	if err != nil || !fInfo.Mode().IsRegular() {
+		return false
+	}
+	// Zip archives store modification times with a precision of seconds at best
+	return fInfo.Size() == fHdr.info.Size() && fInfo.ModTime().Truncate(time.Second).Equal(fHdr.info.ModTime().Truncate(time.Second))
+}
@@ -0,0 +1,46 @@
+package downloadextract
+
+import "os"
+
+const (
+	// defaultDirMode is the permission of created directories, unless SetDirMode is used.
+	defaultDirMode os.FileMode = 0755
+	// defaultFileMode keeps the permissions of files as stored in the archive, unless SetFileMode is used.
+	defaultFileMode os.FileMode = 0777
+)
+
+// WithDirMode is the Option equivalent of SetDirMode.
+func WithDirMode(mode os.FileMode) Option {
+	return func(d *DownloadExtractor) {
+		d.dirMode = mode.Perm()
+	}
+}
+
+// SetDirMode sets the permissions of the directories created while extracting, which are 0755 by default.
+// As usual, the umask of the process is applied on top.
+func (d *DownloadExtractor) SetDirMode(mode os.FileMode) {
+	WithDirMode(mode)(d)
+}
+
+// WithFileMode is the Option equivalent of SetFileMode.
+func WithFileMode(mode os.FileMode) Option {
+	return func(d *DownloadExtractor) {
+		d.fileMode = mode.Perm()
+	}
+}
+
+// SetFileMode clamps the permissions of extracted files, which are taken from the archive, to mode.
+// For example 0755 strips write permissions for group and others, but keeps executable bits set in the archive.
+// As for directories, the umask of the process is applied on top, also to the permissions zip archives store at their end.
+// On Windows the permissions of the archive are ignored and files are always created writable.
+func (d *DownloadExtractor) SetFileMode(mode os.FileMode) {
+	WithFileMode(mode)(d)
+}
+
+// filePerm returns the permissions an extracted file of the archive entry fHdr is created with.
+func (d *DownloadExtractor) filePerm(fHdr *entry) os.FileMode {
+	if !preserveModes {
+		return 0666
+	}
+	return fHdr.info.Mode().Perm() & d.fileMode &^ processUmask()
+}
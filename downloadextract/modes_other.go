@@ -0,0 +1,6 @@
+//go:build !windows
+
+package downloadextract
+
+// preserveModes is true on Unix-like systems, where the permissions of the archive, most importantly the executable bits of binaries like chrome, are applied to extracted files.
+const preserveModes = true
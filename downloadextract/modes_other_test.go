@@ -0,0 +1,49 @@
+//go:build !windows
+
+package downloadextract
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// On Unix-like systems the permissions of the archive are applied, so the chrome binary stays executable.
+func TestRunFileModes(t *testing.T) {
+	archive := buildZip(t, zip.Deflate,
+		testFile{name: "chrome-linux/chrome", body: "binary", mode: 0755},
+		testFile{name: "chrome-linux/resources.pak", body: "pak", mode: 0644},
+		testFile{name: "chrome-linux/chrome-sandbox", body: "sandbox", mode: 0777},
+	)
+	tests := []struct {
+		name     string
+		fileMode os.FileMode
+		want     map[string]os.FileMode
+	}{
+		{name: "archive modes", fileMode: defaultFileMode, want: map[string]os.FileMode{
+			"chrome": 0755, "resources.pak": 0644, "chrome-sandbox": 0777,
+		}},
+		{name: "clamped", fileMode: 0750, want: map[string]os.FileMode{
+			"chrome": 0750, "resources.pak": 0640, "chrome-sandbox": 0750,
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			outPath := filepath.Join(t.TempDir(), "out")
+			_, err := newTestExtractor(serveArchive(t, archive), outPath, WithOmitTopDirs(1), WithFileMode(test.fileMode)).Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, mode := range test.want {
+				fInfo, err := os.Stat(filepath.Join(outPath, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := mode &^ processUmask(); fInfo.Mode().Perm() != want {
+					t.Errorf("mode of %s = %v, want %v", name, fInfo.Mode().Perm(), want)
+				}
+			}
+		})
+	}
+}
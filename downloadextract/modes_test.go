@@ -0,0 +1,33 @@
+package downloadextract
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunDirMode(t *testing.T) {
+	if !preserveModes {
+		t.Skip("directory permissions are not applied on this platform")
+	}
+	archive := buildZip(t, zip.Deflate,
+		testFile{name: "chrome-linux/", mode: 0777},
+		testFile{name: "chrome-linux/lib/libfoo.so", body: "so"},
+	)
+	outPath := filepath.Join(t.TempDir(), "out")
+	_, err := newTestExtractor(serveArchive(t, archive), outPath, WithDirMode(0750)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := 0750 &^ processUmask()
+	for _, name := range []string{"chrome-linux", "chrome-linux/lib"} {
+		fInfo, err := os.Stat(filepath.Join(outPath, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fInfo.Mode().Perm() != want {
+			t.Errorf("mode of %s = %v, want %v", name, fInfo.Mode().Perm(), want)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+package downloadextract
+
+// preserveModes is false on Windows, which only knows a read-only attribute instead of Unix permissions.
+// Applying the permissions of Unix-authored archives there would merely mark files read-only, which prevents updating and deleting them later.
+const preserveModes = false
@@ -0,0 +1,34 @@
+package downloadextract
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// On Windows the permissions of Unix-authored archives are ignored, so read-only modes do not leave files which cannot be updated.
+func TestRunFileModes(t *testing.T) {
+	archive := buildZip(t, zip.Deflate,
+		testFile{name: "chrome-win/chrome.exe", body: "binary", mode: 0555},
+		testFile{name: "chrome-win/resources.pak", body: "pak", mode: 0444},
+	)
+	outPath := filepath.Join(t.TempDir(), "out")
+	_, err := newTestExtractor(serveArchive(t, archive), outPath, WithOmitTopDirs(1)).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"chrome.exe", "resources.pak"} {
+		fPath := filepath.Join(outPath, name)
+		fInfo, err := os.Stat(fPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fInfo.Mode().Perm()&0200 == 0 {
+			t.Errorf("%s is read-only", name)
+		}
+		if err := os.Remove(fPath); err != nil {
+			t.Error(err)
+		}
+	}
+}
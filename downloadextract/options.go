@@ -0,0 +1,15 @@
+package downloadextract
+
+// Option configures a DownloadExtractor created by New.
+// Every setter of DownloadExtractor has an Option equivalent.
+type Option func(*DownloadExtractor)
+
+// New creates a new DownloadExtractor configured by opts.
+// A http GET request will be sent to url and the contents of the archive extracted to a folder at outPath.
+func New(url string, outPath string, opts ...Option) *DownloadExtractor {
+	d := NewDownloadExtractor(url, outPath)
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
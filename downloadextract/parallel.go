@@ -0,0 +1,207 @@
+package downloadextract
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// WithParallelConnections is the Option equivalent of SetParallelConnections.
+func WithParallelConnections(n int) Option {
+	return func(d *DownloadExtractor) {
+		d.connections = n
+	}
+}
+
+// SetParallelConnections enables downloading the archive with n parallel Range requests covering disjoint parts of it.
+// The parts are written to a file next to the output path, which is extracted once the download is complete, so the archive is no longer streamed.
+// If the server does not advertise "Accept-Ranges: bytes" or the size of the archive, or encodes it, the archive is streamed over a single connection as usual.
+// Values below 2 disable parallel downloads, which is the default. Resumable mode takes precedence, see SetResumable.
+func (d *DownloadExtractor) SetParallelConnections(n int) {
+	WithParallelConnections(n)(d)
+}
+
+// runParallel downloads the archive in segments if the server supports it and extracts it afterwards.
+// ok is false if the server does not support Range requests or HEAD requests, in which case nothing has been done.
+func (d *DownloadExtractor) runParallel(ctx context.Context, result *Result) (ok bool, err error) {
+	resp, err := d.request(ctx, http.MethodHead, d.url, d.conditionalHeader())
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return true, ErrNotModified
+	}
+	size := resp.ContentLength
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Accept-Ranges") != "bytes" || size <= 0 || contentEncoding(resp) != "" {
+		return false, nil
+	}
+	result.ContentLength = size
+	result.ServerMD5, result.ServerCRC32C = parseGoogHash(resp.Header)
+	setValidators(resp, result)
+
+	// The archive is stored next to the extracted files
+	err = d.checkDiskSpace(2 * size)
+	if err != nil {
+		return true, err
+	}
+
+	f, err := os.OpenFile(d.partialPath(), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return true, err
+	}
+	defer os.Remove(d.partialPath())
+	defer f.Close()
+	err = f.Truncate(size)
+	if err != nil {
+		return true, err
+	}
+
+	start := time.Now()
+	err = d.downloadSegments(ctx, f, size)
+	result.NetworkWait = time.Since(start)
+	if err != nil {
+		return true, err
+	}
+
+	if d.checksum != "" {
+		err = d.verifyFile(d.partialPath())
+		if err != nil {
+			return true, err
+		}
+	}
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return true, err
+	}
+	return true, d.extract(ctx, f, result)
+}
+
+// downloadSegments fills f with the archive of the given size, downloading one segment per connection.
+// The first error cancels all other segments.
+func (d *DownloadExtractor) downloadSegments(parent context.Context, f *os.File, size int64) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	n := int64(d.connections)
+	if n > size {
+		n = size
+	}
+	segmentSize := (size + n - 1) / n
+
+	var progress *sharedProgress
+	if callback := d.progressFunc(); callback != nil {
+		progress = &sharedProgress{callback: callback, total: size}
+	}
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for start := int64(0); start < size; start += segmentSize {
+		end := start + segmentSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		wg.Add(1)
+		go func(start, end int64) {
+			defer wg.Done()
+			err := d.downloadSegment(ctx, f, start, end, progress)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(start, end)
+	}
+	wg.Wait()
+
+	if err := parent.Err(); err != nil {
+		return err
+	}
+	return firstErr
+}
+
+// downloadSegment writes the bytes from start to end, inclusive, of the archive to the same offsets of f.
+func (d *DownloadExtractor) downloadSegment(ctx context.Context, f *os.File, start, end int64, progress *sharedProgress) error {
+	header := http.Header{}
+	header.Set("Range", "bytes="+strconv.FormatInt(start, 10)+"-"+strconv.FormatInt(end, 10))
+	resp, err := d.get(ctx, d.url, header)
+	if err != nil {
+		return transferError(ctx, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return statusError(resp)
+	}
+
+	counter := &countingReader{r: resp.Body}
+	var body io.Reader = io.LimitReader(counter, end-start+1)
+	if d.maxBytesPerSecond > 0 {
+		body = newThrottledReader(body, d.maxBytesPerSecond/int64(d.connections)+1)
+	}
+	n, err := d.copyBuffer(&segmentWriter{f: f, offset: start}, &segmentReader{r: body, progress: progress})
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	if counter.err != nil {
+		return transferError(ctx, counter.readError(end-start+1))
+	}
+	if err != nil {
+		return err
+	}
+	if n != end-start+1 {
+		return transferError(ctx, fmt.Errorf("%w: segment at byte %v ended after %v of %v bytes", ErrTruncated, start, n, end-start+1))
+	}
+	return nil
+}
+
+// segmentWriter writes to f, starting at offset.
+type segmentWriter struct {
+	f      *os.File
+	offset int64
+}
+
+func (s *segmentWriter) Write(b []byte) (int, error) {
+	n, err := s.f.WriteAt(b, s.offset)
+	s.offset += int64(n)
+	return n, err
+}
+
+// segmentReader reports the bytes read from r to the progress shared by all segments.
+type segmentReader struct {
+	r        io.Reader
+	progress *sharedProgress
+}
+
+func (s *segmentReader) Read(b []byte) (int, error) {
+	n, err := s.r.Read(b)
+	if s.progress != nil {
+		s.progress.add(int64(n))
+	}
+	return n, err
+}
+
+// sharedProgress sums up the bytes downloaded by several segments and reports them through a ProgressFunc.
+type sharedProgress struct {
+	mu         sync.Mutex
+	callback   ProgressFunc
+	total      int64
+	read       int64
+	lastReport int64
+}
+
+func (p *sharedProgress) add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.read += n
+	if p.read-p.lastReport >= progressInterval || p.read == p.total {
+		p.lastReport = p.read
+		p.callback(p.read, p.total)
+	}
+}
@@ -0,0 +1,53 @@
+package downloadextract
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ProbeResult describes the archive at the URL of a DownloadExtractor, as reported by the server.
+type ProbeResult struct {
+	// ContentLength is the size of the archive in bytes as it is transferred, or -1 if the server does not state it.
+	ContentLength int64
+	// LastModified is the time the archive was last modified, or the zero time if the server does not state it.
+	LastModified time.Time
+}
+
+// WithPreflight is the Option equivalent of SetPreflight.
+func WithPreflight(b bool) Option {
+	return func(d *DownloadExtractor) {
+		d.preflight = b
+	}
+}
+
+// SetPreflight enables, when set to true, a HEAD request before a streamed download, as done by Probe.
+// The run fails right away if the archive does not exist.
+// If the response to the download does not state the size of the archive, the size reported by the HEAD request is used for the disk space check and progress.
+func (d *DownloadExtractor) SetPreflight(b bool) {
+	WithPreflight(b)(d)
+}
+
+// Probe sends a HEAD request to the URL of d and returns what the server reports about the archive, without downloading it.
+// An error is returned if the server responds with a status other than 200 OK, like for an archive which does not exist.
+func (d *DownloadExtractor) Probe() (ProbeResult, error) {
+	return d.ProbeContext(context.Background())
+}
+
+// ProbeContext is like Probe, but aborts the request as soon as ctx is done.
+func (d *DownloadExtractor) ProbeContext(ctx context.Context) (ProbeResult, error) {
+	resp, err := d.request(ctx, http.MethodHead, d.url, nil)
+	if err != nil {
+		return ProbeResult{ContentLength: -1}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ProbeResult{ContentLength: -1}, statusError(resp)
+	}
+
+	probe := ProbeResult{ContentLength: resp.ContentLength}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		probe.LastModified = t
+	}
+	return probe, nil
+}
@@ -0,0 +1,37 @@
+package downloadextract
+
+import "io"
+
+// progressInterval is the number of bytes after which the progress callback is invoked again.
+const progressInterval = 64 * 1024
+
+// ProgressFunc is called with the number of bytes downloaded so far and the total size of the download.
+// totalBytes is -1 if the size is unknown.
+type ProgressFunc func(bytesDownloaded, totalBytes int64)
+
+// progressReader wraps an io.Reader and reports the number of bytes read through a ProgressFunc.
+type progressReader struct {
+	r          io.Reader
+	callback   ProgressFunc
+	total      int64
+	read       int64
+	lastReport int64
+}
+
+func newProgressReader(r io.Reader, total int64, callback ProgressFunc) *progressReader {
+	return &progressReader{
+		r:        r,
+		callback: callback,
+		total:    total,
+	}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	if p.read-p.lastReport >= progressInterval || (err == io.EOF && p.read != p.lastReport) {
+		p.lastReport = p.read
+		p.callback(p.read, p.total)
+	}
+	return n, err
+}
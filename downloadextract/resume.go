@@ -0,0 +1,225 @@
+package downloadextract
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	partialExt = ".part"
+	offsetExt  = ".offset"
+
+	// offsetSyncInterval is the number of bytes after which the partial archive is synced to disk and its offset recorded.
+	offsetSyncInterval = 4 * 1024 * 1024
+)
+
+// WithResumable is the Option equivalent of SetResumable.
+func WithResumable(b bool) Option {
+	return func(d *DownloadExtractor) {
+		d.resumable = b
+	}
+}
+
+// SetResumable enables, when set to true, resuming interrupted downloads.
+// Instead of streaming the archive right into the extraction, it is first downloaded to a file next to the output path, with the number of bytes received stored in a sidecar file.
+// If a download fails, a subsequent run continues it with a http Range request, as long as the server answers with 206 Partial Content.
+// Otherwise the archive is downloaded again from the start.
+func (d *DownloadExtractor) SetResumable(b bool) {
+	WithResumable(b)(d)
+}
+
+// partialPath returns the path of the file the archive is downloaded to in resumable mode, or to buffer it, see SetTempFile.
+func (d *DownloadExtractor) partialPath() string {
+	if d.tempFile != "" {
+		return d.tempFile
+	}
+	return d.outPath + partialExt
+}
+
+// runResumable downloads the archive to a file, continuing a previous attempt if there is one, and extracts or saves it afterwards.
+func (d *DownloadExtractor) runResumable(ctx context.Context, result *Result) error {
+	start := time.Now()
+	err := d.download(ctx, result)
+	result.NetworkWait = time.Since(start)
+	if err != nil {
+		return err
+	}
+
+	// A complete archive is never resumed, so it is removed regardless of the outcome of the extraction.
+	defer os.Remove(d.partialPath() + offsetExt)
+	defer os.Remove(d.partialPath())
+
+	if d.checksum != "" {
+		err = d.verifyFile(d.partialPath())
+		if err != nil {
+			return err
+		}
+	}
+
+	if d.archiveOnly {
+		return d.savePartial(result)
+	}
+
+	f, err := os.Open(d.partialPath())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return d.extract(ctx, f, result)
+}
+
+// download fetches the archive into the partial file, starting at the recorded offset.
+// The size and checksums of the complete archive announced by the server are stored in result.
+func (d *DownloadExtractor) download(ctx context.Context, result *Result) error {
+	offset := readOffset(d.partialPath() + offsetExt)
+
+	header := d.conditionalHeader()
+	if offset > 0 {
+		header = http.Header{}
+		header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+	resp, err := d.get(ctx, d.url, header)
+	if err != nil {
+		return transferError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		d.logger.Printf("Resuming download at byte %v\n", offset)
+	case http.StatusOK, http.StatusRequestedRangeNotSatisfiable:
+		if offset > 0 {
+			d.logger.Printf("Server does not support resuming the download, starting over\n")
+		}
+		offset = 0
+		if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			resp.Body.Close()
+			resp, err = d.get(ctx, d.url, d.conditionalHeader())
+			if err != nil {
+				return transferError(ctx, err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode == http.StatusNotModified {
+				return ErrNotModified
+			}
+			if resp.StatusCode != http.StatusOK {
+				return statusError(resp)
+			}
+		}
+	case http.StatusNotModified:
+		return ErrNotModified
+	default:
+		return statusError(resp)
+	}
+
+	// Offsets of encoded responses do not correspond to the archive, so they cannot be resumed
+	if encoding := contentEncoding(resp); encoding != "" {
+		return fmt.Errorf("resumable downloads do not support Content-Encoding \"%s\"", encoding)
+	}
+	if resp.ContentLength >= 0 {
+		result.ContentLength = offset + resp.ContentLength
+	}
+	result.ServerMD5, result.ServerCRC32C = parseGoogHash(resp.Header)
+	setValidators(resp, result)
+
+	// The rest of the archive is stored next to the extracted files
+	if resp.ContentLength >= 0 {
+		err = d.checkDiskSpace(2*resp.ContentLength + offset)
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(d.partialPath(), os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = f.Truncate(offset)
+	if err != nil {
+		return err
+	}
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	counter := &countingReader{r: resp.Body}
+	var body io.Reader = counter
+	if d.maxBytesPerSecond > 0 {
+		body = newThrottledReader(body, d.maxBytesPerSecond)
+	}
+	if progress := d.progressFunc(); progress != nil {
+		total := int64(-1)
+		if resp.ContentLength >= 0 {
+			total = offset + resp.ContentLength
+		}
+		pr := newProgressReader(body, total, progress)
+		pr.read = offset
+		pr.lastReport = offset
+		body = pr
+	}
+
+	oW := &offsetWriter{f: f, path: d.partialPath() + offsetExt, offset: offset, synced: offset}
+	n, err := d.copyBuffer(oW, body)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	} else if counter.err != nil {
+		err = transferError(ctx, counter.readError(resp.ContentLength))
+	}
+	if err == nil {
+		err = transferError(ctx, checkLength(n, resp.ContentLength))
+	}
+	// Record the final offset, even if the download failed, so the next run can pick up from there.
+	if e := oW.sync(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// offsetWriter writes to the partial archive and periodically records the number of bytes which are safely on disk.
+type offsetWriter struct {
+	f      *os.File
+	path   string
+	offset int64
+	synced int64
+}
+
+func (o *offsetWriter) Write(b []byte) (int, error) {
+	n, err := o.f.Write(b)
+	o.offset += int64(n)
+	if err == nil && o.offset-o.synced >= offsetSyncInterval {
+		err = o.sync()
+	}
+	return n, err
+}
+
+func (o *offsetWriter) sync() error {
+	err := o.f.Sync()
+	if err != nil {
+		return err
+	}
+	o.synced = o.offset
+	return ioutil.WriteFile(o.path, []byte(strconv.FormatInt(o.offset, 10)), 0644)
+}
+
+// readOffset returns the offset recorded in the sidecar file at path, or 0 if there is none.
+func readOffset(path string) int64 {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	offset, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil || offset < 0 {
+		return 0
+	}
+	return offset
+}
@@ -0,0 +1,101 @@
+package downloadextract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fried-ice/chromiumup/internal/backoff"
+)
+
+// maxErrorBodySnippet is the number of bytes of an error response body included in the error message.
+const maxErrorBodySnippet = 256
+
+// ErrNotFound is wrapped by the error returned if the server responds to a request of the archive with 404 Not Found, like for a build which does not exist.
+var ErrNotFound = errors.New("archive not found")
+
+// errServerStatus is wrapped by the error of a request answered with a server error, which is worth retrying.
+var errServerStatus = errors.New("server responded with HTTP status")
+
+// WithRetries is the Option equivalent of SetRetries.
+func WithRetries(count int, baseDelay time.Duration) Option {
+	return func(d *DownloadExtractor) {
+		d.retries = count
+		d.retryDelay = baseDelay
+	}
+}
+
+// SetRetries enables retrying the download request up to count times on network errors and 5xx responses.
+// The delay before the n-th retry is baseDelay * 2^(n-1).
+// Retries only happen before the first byte of the archive has been handed to the extraction, so a connection dropping mid-stream still fails the run.
+// Recovering from such failures requires the server to support Range requests and is not covered by retries.
+func (d *DownloadExtractor) SetRetries(count int, baseDelay time.Duration) {
+	WithRetries(count, baseDelay)(d)
+}
+
+// get sends a GET request to url with the http client of d and retries it according to SetRetries.
+// The values of header are added to the request.
+// The returned response is successful in terms of not being a server error.
+func (d *DownloadExtractor) get(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	return d.request(ctx, http.MethodGet, url, header)
+}
+
+// request is like get, but sends a request with the given method.
+func (d *DownloadExtractor) request(ctx context.Context, method string, url string, header http.Header) (*http.Response, error) {
+	var resp *http.Response
+	err := backoff.Retry(ctx, d.retries, d.retryDelay, func() error {
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if d.userAgent != "" {
+			req.Header.Set("User-Agent", d.userAgent)
+		}
+
+		resp, err = d.client.Do(req)
+		if err != nil {
+			return transferError(ctx, err)
+		}
+		if resp.StatusCode >= http.StatusInternalServerError {
+			resp.Body.Close()
+			return fmt.Errorf("%w %s", errServerStatus, resp.Status)
+		}
+		return nil
+	}, retryable, func(err error, delay time.Duration) {
+		d.logger.Printf("Request failed (%v), retrying in %v\n", err, delay)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// retryable reports whether a request failing with err may succeed when sent again.
+// This is the case for TransferErrors, like a refused or dropped connection, and server errors.
+func retryable(err error) bool {
+	var transferErr *TransferError
+	return errors.As(err, &transferErr) || errors.Is(err, errServerStatus)
+}
+
+// statusError returns an error describing the unexpected status of resp, including the beginning of its body.
+// Error responses of storage servers usually explain the problem, like a missing object or lacking permissions.
+// The error of a 404 Not Found response wraps ErrNotFound.
+func statusError(resp *http.Response) error {
+	b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySnippet))
+	msg := "server responded with HTTP status " + resp.Status
+	if snippet := strings.Join(strings.Fields(string(b)), " "); snippet != "" {
+		msg += ": " + snippet
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, msg)
+	}
+	return errors.New(msg)
+}
@@ -0,0 +1,60 @@
+package downloadextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunRetries(t *testing.T) {
+	archive := buildZip(t, zip.Deflate, testFile{name: "chrome-linux/chrome", body: "binary"})
+	// The first request is dropped without a response, the second one fails with a server error
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&requests, 1) {
+		case 1:
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			http.ServeContent(w, r, "", testTime, bytes.NewReader(archive))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		retries int
+		ok      bool
+	}{
+		{retries: 1, ok: false},
+		{retries: 2, ok: true},
+	}
+	for _, test := range tests {
+		atomic.StoreInt32(&requests, 0)
+		_, err := newTestExtractor(srv.URL, filepath.Join(t.TempDir(), "out"), WithRetries(test.retries, time.Millisecond)).Run()
+		if ok := err == nil; ok != test.ok {
+			t.Errorf("Run with %v retries returned %v", test.retries, err)
+		}
+	}
+}
+
+func TestRunNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	for _, opt := range []Option{WithResumable(false), WithResumable(true), WithPreflight(true)} {
+		_, err := newTestExtractor(srv.URL, filepath.Join(t.TempDir(), "out"), opt).Run()
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Run returned %v, want ErrNotFound", err)
+		}
+	}
+}
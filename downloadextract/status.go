@@ -0,0 +1,69 @@
+package downloadextract
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/fried-ice/chromiumup/internal/display"
+)
+
+const (
+	// terminalStatusInterval is the minimum time between updates of the status line on a terminal.
+	terminalStatusInterval = 100 * time.Millisecond
+	// plainStatusInterval is the time between status lines if the output is no terminal.
+	plainStatusInterval = 5 * time.Second
+)
+
+// WithStatusOutput is the Option equivalent of SetStatusOutput.
+func WithStatusOutput(w io.Writer) Option {
+	return func(d *DownloadExtractor) {
+		d.statusOutput = w
+	}
+}
+
+// SetStatusOutput enables printing the number of extracted files and bytes to w while extracting.
+// If w is a terminal, a single line is updated in place, otherwise a line is printed every few seconds.
+func (d *DownloadExtractor) SetStatusOutput(w io.Writer) {
+	WithStatusOutput(w)(d)
+}
+
+// statusPrinter prints the progress of an extraction in a throttled way.
+type statusPrinter struct {
+	w        io.Writer
+	terminal bool
+	interval time.Duration
+	last     time.Time
+	printed  bool
+}
+
+func newStatusPrinter(w io.Writer) *statusPrinter {
+	s := &statusPrinter{w: w, interval: plainStatusInterval, last: time.Now()}
+	if f, ok := w.(*os.File); ok && display.IsTerminal(f) {
+		s.terminal = true
+		s.interval = terminalStatusInterval
+	}
+	return s
+}
+
+// update prints the current status, unless the last one was printed too recently.
+func (s *statusPrinter) update(files int, bytes int64) {
+	if time.Since(s.last) < s.interval {
+		return
+	}
+	s.last = time.Now()
+	s.printed = true
+	if s.terminal {
+		fmt.Fprintf(s.w, "\rExtracted %v files, %s", files, display.FormatBytes(uint64(bytes)))
+	} else {
+		fmt.Fprintf(s.w, "Extracted %v files, %s so far\n", files, display.FormatBytes(uint64(bytes)))
+	}
+}
+
+// finish terminates the status line on a terminal, so subsequent output starts on a new line.
+func (s *statusPrinter) finish() {
+	if s.terminal && s.printed {
+		fmt.Fprintln(s.w)
+	}
+}
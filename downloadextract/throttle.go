@@ -0,0 +1,58 @@
+package downloadextract
+
+import (
+	"io"
+	"time"
+)
+
+// WithMaxBytesPerSecond is the Option equivalent of SetMaxBytesPerSecond.
+func WithMaxBytesPerSecond(n int64) Option {
+	return func(d *DownloadExtractor) {
+		d.maxBytesPerSecond = n
+	}
+}
+
+// SetMaxBytesPerSecond limits the download bandwidth to n bytes per second.
+// A value of 0 means unlimited, which is the default.
+func (d *DownloadExtractor) SetMaxBytesPerSecond(n int64) {
+	WithMaxBytesPerSecond(n)(d)
+}
+
+// throttledReader limits the rate at which data is read from r by means of a token bucket.
+// The bucket holds up to one second worth of data, so short bursts are allowed.
+type throttledReader struct {
+	r      io.Reader
+	rate   int64
+	tokens float64
+	last   time.Time
+}
+
+func newThrottledReader(r io.Reader, bytesPerSecond int64) *throttledReader {
+	return &throttledReader{
+		r:      r,
+		rate:   bytesPerSecond,
+		tokens: float64(bytesPerSecond),
+		last:   time.Now(),
+	}
+}
+
+func (t *throttledReader) Read(b []byte) (int, error) {
+	if int64(len(b)) > t.rate {
+		b = b[:t.rate]
+	}
+
+	now := time.Now()
+	t.tokens += now.Sub(t.last).Seconds() * float64(t.rate)
+	if t.tokens > float64(t.rate) {
+		t.tokens = float64(t.rate)
+	}
+	t.last = now
+
+	n, err := t.r.Read(b)
+	t.tokens -= float64(n)
+	if t.tokens < 0 {
+		// Only the reading goroutine sleeps, so a slow extraction cannot block the refill
+		time.Sleep(time.Duration(-t.tokens / float64(t.rate) * float64(time.Second)))
+	}
+	return n, err
+}
@@ -0,0 +1,32 @@
+package downloadextract
+
+import (
+	"io"
+	"time"
+)
+
+// timedReader adds the time spent reading from r to wait.
+type timedReader struct {
+	r    io.Reader
+	wait *time.Duration
+}
+
+func (t *timedReader) Read(b []byte) (int, error) {
+	start := time.Now()
+	n, err := t.r.Read(b)
+	*t.wait += time.Since(start)
+	return n, err
+}
+
+// timedWriter adds the time spent writing to w to wait.
+type timedWriter struct {
+	w    io.Writer
+	wait *time.Duration
+}
+
+func (t *timedWriter) Write(b []byte) (int, error) {
+	start := time.Now()
+	n, err := t.w.Write(b)
+	*t.wait += time.Since(start)
+	return n, err
+}
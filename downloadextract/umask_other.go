@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd
+
+package downloadextract
+
+import "os"
+
+// processUmask always returns 0, as there is no umask or no supported way to read it.
+func processUmask() os.FileMode {
+	return 0
+}
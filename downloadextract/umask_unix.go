@@ -0,0 +1,50 @@
+//go:build linux || darwin || freebsd
+
+package downloadextract
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+var (
+	umaskOnce sync.Once
+	umask     os.FileMode
+)
+
+// processUmask returns the umask of the process, which is determined once.
+// Linux reports it in /proc, elsewhere it can only be read by setting it, so it is restored right away.
+// Files created by other goroutines in between are not restricted by the umask.
+func processUmask() os.FileMode {
+	umaskOnce.Do(func() {
+		if m, ok := procUmask(); ok {
+			umask = m
+			return
+		}
+		m := syscall.Umask(0)
+		syscall.Umask(m)
+		umask = os.FileMode(m).Perm()
+	})
+	return umask
+}
+
+// procUmask reads the umask of the process from /proc/self/status, which is supported by Linux 4.7 and later.
+func procUmask() (os.FileMode, bool) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if value := strings.TrimPrefix(s.Text(), "Umask:"); value != s.Text() {
+			m, err := strconv.ParseUint(strings.TrimSpace(value), 8, 32)
+			return os.FileMode(m).Perm(), err == nil
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,44 @@
+//go:build linux || darwin || freebsd
+
+package downloadextract
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+// setUmask sets the umask of the process to mask for the duration of the test and resets the value cached by processUmask.
+func setUmask(t *testing.T, mask int) {
+	old := syscall.Umask(mask)
+	umaskOnce, umask = sync.Once{}, 0
+	t.Cleanup(func() {
+		syscall.Umask(old)
+		umaskOnce, umask = sync.Once{}, 0
+	})
+}
+
+func TestRunUmask(t *testing.T) {
+	setUmask(t, 077)
+	archive := buildZip(t, zip.Deflate,
+		testFile{name: "chrome-linux/", mode: 0755},
+		testFile{name: "chrome-linux/chrome", body: "binary", mode: 0755},
+	)
+	outPath := filepath.Join(t.TempDir(), "out")
+	_, err := newTestExtractor(serveArchive(t, archive), outPath).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"chrome-linux", "chrome-linux/chrome"} {
+		fInfo, err := os.Stat(filepath.Join(outPath, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fInfo.Mode().Perm() != 0700 {
+			t.Errorf("mode of %s = %v, want 0700", name, fInfo.Mode().Perm())
+		}
+	}
+}
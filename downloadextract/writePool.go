@@ -0,0 +1,104 @@
+package downloadextract
+
+import (
+	"bytes"
+	"sync"
+)
+
+// WithConcurrency is the Option equivalent of SetConcurrency.
+func WithConcurrency(n int) Option {
+	return func(d *DownloadExtractor) {
+		d.concurrency = n
+	}
+}
+
+// SetConcurrency sets the number of goroutines writing extracted files to disk.
+// Archives are still decompressed sequentially, but with n greater than 1 the decompressed content of every file is buffered in memory and handed to one of n writers, so disk IO overlaps with decompression.
+// Note that this requires memory for up to n files at once, which includes the largest file of the archive.
+func (d *DownloadExtractor) SetConcurrency(n int) {
+	WithConcurrency(n)(d)
+}
+
+// writeJob is a regular file whose decompressed content is waiting to be written.
+type writeJob struct {
+	fPath string
+	fHdr  *entry
+	data  []byte
+}
+
+// writePool writes files with a fixed number of goroutines.
+// After the first failure, remaining jobs are discarded, unless SetContinueOnError allows the failure.
+type writePool struct {
+	d    *DownloadExtractor
+	jobs chan writeJob
+	wg   sync.WaitGroup
+	once sync.Once
+
+	mu  sync.Mutex
+	err error
+	// fileErrors are the files which could not be written with SetContinueOnError, with failedBytes being the sum of their sizes.
+	fileErrors  []FileError
+	failedBytes int64
+}
+
+// newWritePool starts n writing goroutines for d.
+func (d *DownloadExtractor) newWritePool(n int) *writePool {
+	p := &writePool{
+		d:    d,
+		jobs: make(chan writeJob, n),
+	}
+	p.wg.Add(n)
+	for i := 0; i < n; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *writePool) work() {
+	defer p.wg.Done()
+	for job := range p.jobs {
+		if p.failure() != nil {
+			continue
+		}
+		_, err := p.d.writeFile(job.fPath, job.fHdr, bytes.NewReader(job.data))
+		if err == nil {
+			continue
+		}
+		fileErr, ok := p.d.fileError(job.fHdr, job.fPath, err)
+		p.mu.Lock()
+		if ok {
+			p.failedBytes += int64(len(job.data))
+			p.fileErrors = append(p.fileErrors, fileErr)
+		} else if p.err == nil {
+			p.err = err
+		}
+		p.mu.Unlock()
+	}
+}
+
+// failure returns the first error which occurred while writing a file.
+func (p *writePool) failure() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// submit queues job for writing, blocking if all writers are busy.
+// If writing a previous file failed, its error is returned instead.
+func (p *writePool) submit(job writeJob) error {
+	if err := p.failure(); err != nil {
+		return err
+	}
+	p.jobs <- job
+	return nil
+}
+
+// wait blocks until all queued files are written and returns the first error which occurred.
+// It may be called multiple times.
+func (p *writePool) wait() error {
+	p.once.Do(func() {
+		close(p.jobs)
+	})
+	p.wg.Wait()
+	return p.failure()
+}
@@ -0,0 +1,88 @@
+package downloadextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"hash/crc32"
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// buildDeflatedZip returns a zip archive of files, which are deflated up front.
+// Like in the Chromium archives, the sizes are stored in the local headers instead of a data descriptor.
+func buildDeflatedZip(b *testing.B, files ...testFile) []byte {
+	b.Helper()
+	var buf bytes.Buffer
+	zW := zip.NewWriter(&buf)
+	for _, f := range files {
+		var compressed bytes.Buffer
+		fW, err := flate.NewWriter(&compressed, flate.DefaultCompression)
+		if err != nil {
+			b.Fatal(err)
+		}
+		fW.Write([]byte(f.body))
+		fW.Close()
+		hdr := &zip.FileHeader{
+			Name:               f.name,
+			Method:             zip.Deflate,
+			Modified:           testTime,
+			CRC32:              crc32.ChecksumIEEE([]byte(f.body)),
+			CompressedSize64:   uint64(compressed.Len()),
+			UncompressedSize64: uint64(len(f.body)),
+		}
+		hdr.SetMode(f.fileMode())
+		w, err := zW.CreateRaw(hdr)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_, err = w.Write(compressed.Bytes())
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	err := zW.Close()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// compressibleBody returns n bytes of content, which deflates to about half of its size.
+func compressibleBody(n int) string {
+	b := make([]byte, n)
+	r := rand.New(rand.NewSource(int64(n)))
+	for i := range b {
+		b[i] = 'a' + byte(r.Intn(16))
+	}
+	return string(b)
+}
+
+func BenchmarkRunConcurrency(b *testing.B) {
+	// Like the Chromium archive, many small files next to a few large ones
+	var files []testFile
+	for i := 0; i < 256; i++ {
+		size := 16 * 1024
+		if i%32 == 0 {
+			size = 4 * 1024 * 1024
+		}
+		files = append(files, testFile{name: "chrome-linux/file" + strconv.Itoa(i), body: compressibleBody(size + i)})
+	}
+	archive := buildDeflatedZip(b, files...)
+	url := serveArchive(b, archive)
+
+	// 1 is the serial path, which writes every file while it is decompressed
+	for _, n := range []int{1, 4, 8} {
+		b.Run("n="+strconv.Itoa(n), func(b *testing.B) {
+			b.SetBytes(int64(len(archive)))
+			for i := 0; i < b.N; i++ {
+				_, err := newTestExtractor(url, filepath.Join(b.TempDir(), "out"), WithConcurrency(n)).Run()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
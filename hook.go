@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// shellCommand creates a command running command with the shell of the platform.
+// The variables of env are added to the environment of the process, stdin and stderr are connected to the ones of the process and stdout to stdout.
+func shellCommand(command string, env ...string) *exec.Cmd {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// runPostInstall runs the -post-install command for build revision of platform installed at installPath.
+func runPostInstall(command string, installPath string, revision string, platform string) error {
+	cmd := shellCommand(command, "CHROMIUMUP_INSTALL_DIR="+installPath, "CHROMIUMUP_REVISION="+revision, "CHROMIUMUP_PLATFORM="+platform)
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("post-install command failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"syscall"
+)
+
+// rename is used to move directories, it can be replaced to simulate failures.
+var rename = os.Rename
+
+// phase is a step of an installation.
+type phase int
+
+const (
+	// phaseExtract means the build is being extracted to the temporary path.
+	phaseExtract phase = iota
+	// phaseSwap means the original directory has been moved aside, but the new build is not in place yet.
+	phaseSwap
+	// phaseInstalled means the new build is in place and the original directory is waiting to be deleted.
+	phaseInstalled
+)
+
+// installation tracks the phase of an installation, so an interruption can be cleaned up according to it.
+// The mutex is held during every step which changes the file system, so cleanup never observes a step halfway done.
+// Moving the new build into place may take a while if it has to be copied, so it holds moving instead.
+// Cleanup waits for it, but knows that the swap is in progress.
+type installation struct {
+	tmpPath    string
+	targetPath string
+	// keepOld keeps the original directory at targetPath+oldExt if the installation is interrupted after the new build is in place,
+	// as it is supposed to be kept there or moved on by the caller.
+	keepOld bool
+	// keepTmp keeps the build at tmpPath if the installation fails or is interrupted, as it is a backup being restored rather than a download.
+	keepTmp bool
+	// replaced reports whether install moved an original directory aside.
+	replaced bool
+
+	mu     sync.Mutex
+	phase  phase
+	moving sync.Mutex
+}
+
+// newInstallation creates an installation extracting to tmpPath and installing to targetPath.
+func newInstallation(tmpPath string, targetPath string) *installation {
+	return &installation{tmpPath: tmpPath, targetPath: targetPath}
+}
+
+// setTarget changes the path the build is installed to.
+func (i *installation) setTarget(targetPath string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.targetPath = targetPath
+}
+
+// install moves the extracted directory tmpPath to targetPath.
+// If there is no such directory, we will simply rename the downloaded folder to its target path.
+// If there is, rename existing directory and rename downloaded directory to target path.
+// If this fails, try to restore the original directory and delete the downloaded files.
+// pathExisted reports whether the original directory was kept at targetPath+oldExt, where the caller is supposed to delete it.
+// An original directory kept there by an earlier installation is replaced.
+func (i *installation) install() (pathExisted bool, err error) {
+	i.mu.Lock()
+	pathExisted = pathExists(i.targetPath)
+	if pathExisted {
+		err = os.RemoveAll(i.targetPath + oldExt)
+		if err != nil {
+			return false, err
+		}
+		err = rename(i.targetPath, i.targetPath+oldExt)
+		if err != nil {
+			i.mu.Unlock()
+			return false, err
+		}
+		i.phase = phaseSwap
+	}
+	i.moving.Lock()
+	i.mu.Unlock()
+
+	err = moveDir(i.tmpPath, i.targetPath)
+	i.moving.Unlock()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if err != nil {
+		// Restore previous state and remove downloaded files
+		if pathExisted {
+			rename(i.targetPath+oldExt, i.targetPath)
+		}
+		if !i.keepTmp {
+			os.RemoveAll(i.tmpPath)
+		}
+		i.phase = phaseExtract
+		return false, err
+	}
+	i.phase = phaseInstalled
+	i.replaced = pathExisted
+	return pathExisted, nil
+}
+
+// revert undoes a completed installation by deleting the new build and restoring the original directory, if there was one.
+// It must be called before the caller deletes the original directory.
+func (i *installation) revert() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.phase != phaseInstalled {
+		return errors.New("build is not installed")
+	}
+	err := os.RemoveAll(i.targetPath)
+	if err != nil {
+		return err
+	}
+	if i.replaced {
+		err = rename(i.targetPath+oldExt, i.targetPath)
+		if err != nil {
+			return err
+		}
+	}
+	// Nothing is left to clean up on an interruption
+	i.phase = phaseExtract
+	return nil
+}
+
+// interrupt cleans up after an interruption in whatever phase the installation is.
+// Nothing is printed if there is nothing to clean up, as on returning from a finished run.
+// It keeps the mutex locked, so no further step is taken afterwards.
+func (i *installation) interrupt() {
+	i.mu.Lock()
+	// Wait for the new build to be moved, so it is not modified while it is removed
+	i.moving.Lock()
+	defer i.moving.Unlock()
+	switch i.phase {
+	case phaseExtract:
+		if i.keepTmp || !pathExists(i.tmpPath) {
+			break
+		}
+		println("Deleting temporary folder " + i.tmpPath)
+		os.RemoveAll(i.tmpPath)
+	case phaseSwap:
+		println("Restoring original folder " + i.targetPath)
+		if i.keepTmp && !pathExists(i.tmpPath) {
+			// The kept build has been moved into place already, so it is moved back
+			rename(i.targetPath, i.tmpPath)
+		} else {
+			os.RemoveAll(i.targetPath)
+		}
+		rename(i.targetPath+oldExt, i.targetPath)
+		if !i.keepTmp {
+			os.RemoveAll(i.tmpPath)
+		}
+	case phaseInstalled:
+		if i.keepOld || !pathExists(i.targetPath+oldExt) {
+			break
+		}
+		println("Deleting old folder " + i.targetPath + oldExt)
+		os.RemoveAll(i.targetPath + oldExt)
+	}
+}
+
+// removeStaleTmp deletes the temporary directory at tmpPath left behind by an earlier run, which crashed without cleaning up.
+// The new build must not be extracted into it, where it would be mixed with the files of the earlier one.
+// Partial downloads next to it are kept, so a resumable download can pick up from them.
+// removed reports whether there was such a directory.
+func removeStaleTmp(tmpPath string) (removed bool, err error) {
+	if !pathExists(tmpPath) {
+		return false, nil
+	}
+	return true, os.RemoveAll(tmpPath)
+}
+
+// moveDir moves the directory src to dst.
+// If src is located on another filesystem than dst, it is copied to dst and removed afterwards.
+// A partial copy is removed again if copying fails.
+func moveDir(src string, dst string) error {
+	err := rename(src, dst)
+	if err == nil || !isCrossDevice(err) {
+		return err
+	}
+
+	err = copyDir(src, dst)
+	if err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// isCrossDevice reports whether err is caused by renaming a file across filesystems.
+func isCrossDevice(err error) bool {
+	if runtime.GOOS == "windows" {
+		// ERROR_NOT_SAME_DEVICE
+		return errors.Is(err, syscall.Errno(17))
+	}
+	return errors.Is(err, syscall.EXDEV)
+}
+
+// copyDir recursively copies the directory src to dst, preserving modes, modification times and symbolic links.
+func copyDir(src string, dst string) error {
+	var dirs []string
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			dirs = append(dirs, path)
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			return copyFile(path, target, info)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Directory times are set last, as copying their children modifies them
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, dir)
+		err = os.Chtimes(filepath.Join(dst, rel), info.ModTime(), info.ModTime())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies the regular file src described by info to dst.
+func copyFile(src string, dst string, info os.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	err = out.Close()
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+// writeBuild creates a directory at path holding a chrome file with content, which tells builds apart.
+func writeBuild(t *testing.T, path string, content string) {
+	t.Helper()
+	err := os.MkdirAll(path, 0755)
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(path, "chrome"), []byte(content), 0755)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// buildAt returns the content of the chrome file of the build at path, or an empty string if there is none.
+func buildAt(path string) string {
+	b, _ := ioutil.ReadFile(filepath.Join(path, "chrome"))
+	return string(b)
+}
+
+// newTestInstallation returns an installation of a new build at a temporary path replacing an old build at the target path.
+func newTestInstallation(t *testing.T) *installation {
+	dir := t.TempDir()
+	inst := newInstallation(filepath.Join(dir, "chromium-tmp"), filepath.Join(dir, "chromium"))
+	writeBuild(t, inst.tmpPath, "new")
+	writeBuild(t, inst.targetPath, "old")
+	return inst
+}
+
+// replaceRename replaces rename for the duration of the test with one failing with err for renames of src.
+func replaceRename(t *testing.T, src string, err error) {
+	rename = func(oldpath string, newpath string) error {
+		if oldpath == src {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+		}
+		return os.Rename(oldpath, newpath)
+	}
+	t.Cleanup(func() { rename = os.Rename })
+}
+
+// errCrossDevice is the error of renaming a file to another filesystem.
+func errCrossDevice() error {
+	if runtime.GOOS == "windows" {
+		// ERROR_NOT_SAME_DEVICE
+		return syscall.Errno(17)
+	}
+	return syscall.EXDEV
+}
+
+func TestInstall(t *testing.T) {
+	inst := newTestInstallation(t)
+	pathExisted, err := inst.install()
+	if err != nil || !pathExisted {
+		t.Fatalf("install returned %v, %v", pathExisted, err)
+	}
+	if buildAt(inst.targetPath) != "new" || buildAt(inst.targetPath+oldExt) != "old" {
+		t.Errorf("found %q at the target path and %q next to it, want new and old", buildAt(inst.targetPath), buildAt(inst.targetPath+oldExt))
+	}
+	assertNotExist(t, inst.tmpPath)
+}
+
+func TestInstallCrossDevice(t *testing.T) {
+	inst := newTestInstallation(t)
+	err := os.Symlink("chrome", filepath.Join(inst.tmpPath, "chromium"))
+	if err != nil && runtime.GOOS != "windows" {
+		t.Fatal(err)
+	}
+	replaceRename(t, inst.tmpPath, errCrossDevice())
+	pathExisted, err := inst.install()
+	if err != nil || !pathExisted {
+		t.Fatalf("install returned %v, %v", pathExisted, err)
+	}
+	if buildAt(inst.targetPath) != "new" || buildAt(inst.targetPath+oldExt) != "old" {
+		t.Errorf("found %q at the target path and %q next to it, want new and old", buildAt(inst.targetPath), buildAt(inst.targetPath+oldExt))
+	}
+	if link, err := os.Readlink(filepath.Join(inst.targetPath, "chromium")); runtime.GOOS != "windows" && link != "chrome" {
+		t.Errorf("the symbolic link has been copied as %q, %v", link, err)
+	}
+	assertNotExist(t, inst.tmpPath)
+}
+
+func TestInstallRollback(t *testing.T) {
+	t.Run("swap", func(t *testing.T) {
+		inst := newTestInstallation(t)
+		replaceRename(t, inst.tmpPath, errors.New("simulated failure"))
+		_, err := inst.install()
+		if err == nil {
+			t.Fatal("install succeeded despite the failing rename")
+		}
+		if buildAt(inst.targetPath) != "old" {
+			t.Errorf("found %q at the target path, want the old build", buildAt(inst.targetPath))
+		}
+		assertNotExist(t, inst.targetPath+oldExt)
+		assertNotExist(t, inst.tmpPath)
+	})
+	t.Run("copy", func(t *testing.T) {
+		inst := newTestInstallation(t)
+		replaceRename(t, inst.tmpPath, errCrossDevice())
+		if runtime.GOOS == "windows" {
+			t.Skip("copying is made to fail with a Unix socket")
+		}
+		// A socket cannot be opened, so copying fails after the first files
+		l, err := net.Listen("unix", filepath.Join(inst.tmpPath, "socket"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		_, err = inst.install()
+		if err == nil {
+			t.Fatal("install succeeded despite the failing copy")
+		}
+		if buildAt(inst.targetPath) != "old" {
+			t.Errorf("found %q at the target path, want the old build", buildAt(inst.targetPath))
+		}
+		assertNotExist(t, inst.targetPath+oldExt)
+		assertNotExist(t, inst.tmpPath)
+	})
+	t.Run("moving aside", func(t *testing.T) {
+		inst := newTestInstallation(t)
+		replaceRename(t, inst.targetPath, errors.New("simulated failure"))
+		_, err := inst.install()
+		if err == nil {
+			t.Fatal("install succeeded despite the failing rename")
+		}
+		if buildAt(inst.targetPath) != "old" {
+			t.Errorf("found %q at the target path, want the old build", buildAt(inst.targetPath))
+		}
+		assertNotExist(t, inst.targetPath+oldExt)
+	})
+}
+
+// assertNotExist fails the test if path exists.
+func assertNotExist(t *testing.T, path string) {
+	t.Helper()
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("%s exists: %v", path, err)
+	}
+}
+
+// interruptSwap interrupts inst after the new build has been moved into place, before install takes the mutex again.
+func interruptSwap(t *testing.T, inst *installation) {
+	// Moving the new build into place blocks, after the old build has been moved aside
+	moving, proceed := make(chan struct{}), make(chan struct{})
+	rename = func(oldpath string, newpath string) error {
+		if oldpath == inst.tmpPath {
+			close(moving)
+			<-proceed
+		}
+		return os.Rename(oldpath, newpath)
+	}
+	t.Cleanup(func() { rename = os.Rename })
+
+	// install does not return, as interrupt keeps the mutex locked
+	go inst.install()
+	<-moving
+	interrupted := make(chan struct{})
+	go func() {
+		inst.interrupt()
+		close(interrupted)
+	}()
+	// The mutex is free while the new build is moved, until interrupt takes it and waits for the move
+	for inst.mu.TryLock() {
+		inst.mu.Unlock()
+		runtime.Gosched()
+	}
+	close(proceed)
+	<-interrupted
+}
+
+func TestInterrupt(t *testing.T) {
+	t.Run("extract", func(t *testing.T) {
+		inst := newTestInstallation(t)
+		inst.interrupt()
+		assertNotExist(t, inst.tmpPath)
+		if buildAt(inst.targetPath) != "old" {
+			t.Errorf("found %q at the target path, want the old build", buildAt(inst.targetPath))
+		}
+	})
+	t.Run("swap", func(t *testing.T) {
+		inst := newTestInstallation(t)
+		interruptSwap(t, inst)
+		if buildAt(inst.targetPath) != "old" {
+			t.Errorf("found %q at the target path, want the old build", buildAt(inst.targetPath))
+		}
+		assertNotExist(t, inst.targetPath+oldExt)
+		assertNotExist(t, inst.tmpPath)
+	})
+	t.Run("swap keeping the build", func(t *testing.T) {
+		inst := newTestInstallation(t)
+		inst.keepTmp = true
+		interruptSwap(t, inst)
+		if buildAt(inst.targetPath) != "old" || buildAt(inst.tmpPath) != "new" {
+			t.Errorf("found %q at the target path and %q at the temporary path, want the old and the new build", buildAt(inst.targetPath), buildAt(inst.tmpPath))
+		}
+		assertNotExist(t, inst.targetPath+oldExt)
+	})
+	t.Run("installed", func(t *testing.T) {
+		inst := newTestInstallation(t)
+		_, err := inst.install()
+		if err != nil {
+			t.Fatal(err)
+		}
+		inst.interrupt()
+		if buildAt(inst.targetPath) != "new" {
+			t.Errorf("found %q at the target path, want the new build", buildAt(inst.targetPath))
+		}
+		assertNotExist(t, inst.targetPath+oldExt)
+	})
+}
+
+func TestRemoveStaleTmp(t *testing.T) {
+	tmpPath := filepath.Join(t.TempDir(), "chromium"+tmpExt)
+	writeBuild(t, filepath.Join(tmpPath, "chrome-linux"), "stale")
+	err := ioutil.WriteFile(tmpPath+".part", []byte("partial"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := removeStaleTmp(tmpPath)
+	if err != nil || !removed {
+		t.Fatalf("removeStaleTmp returned %v, %v", removed, err)
+	}
+	assertNotExist(t, tmpPath)
+	if _, err := os.Stat(tmpPath + ".part"); err != nil {
+		t.Errorf("the partial download has not been kept: %v", err)
+	}
+
+	removed, err = removeStaleTmp(tmpPath)
+	if err != nil || removed {
+		t.Errorf("removeStaleTmp returned %v, %v without a temporary directory", removed, err)
+	}
+}
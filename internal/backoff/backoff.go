@@ -0,0 +1,30 @@
+// Package backoff retries failed operations with exponentially growing delays, as done for the requests of downloadextract and snapshots.
+package backoff
+
+import (
+	"context"
+	"time"
+)
+
+// Retry calls attempt until it succeeds, up to retries more times if it fails with an error for which retryable returns true.
+// A nil retryable retries every error. The delay before the n-th retry is delay * 2^(n-1).
+// notify, unless it is nil, is called with the error of every attempt about to be retried and the delay before the next one.
+// The error of the last attempt is returned, or the error of ctx if it is done before.
+func Retry(ctx context.Context, retries int, delay time.Duration, attempt func() error, retryable func(err error) bool, notify func(err error, delay time.Duration)) error {
+	for n := 0; ; n++ {
+		err := attempt()
+		if err == nil || n >= retries || ctx.Err() != nil || (retryable != nil && !retryable(err)) {
+			return err
+		}
+		if notify != nil {
+			notify(err, delay)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+}
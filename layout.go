@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const (
+	// layoutRaw installs a build directly at the target path, replacing the previous one.
+	layoutRaw = "raw"
+	// layoutRevision installs every build in a directory named after its revision below the target path.
+	// The symbolic link currentLink within the target path points to the active one.
+	layoutRevision = "revision"
+	// currentLink is the name of the symbolic link to the active build with layoutRevision.
+	currentLink = "current"
+)
+
+// linkCurrent points the symbolic link currentLink within dir to the directory of revision.
+// The link is replaced atomically, so it always points to a complete build.
+func linkCurrent(dir string, revision string) error {
+	tmpLink := filepath.Join(dir, currentLink+tmpExt)
+	os.Remove(tmpLink)
+	// A relative target keeps the link working if dir is moved
+	err := os.Symlink(revision, tmpLink)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmpLink, filepath.Join(dir, currentLink))
+	if err != nil {
+		os.Remove(tmpLink)
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fried-ice/chromiumup/downloadextract"
+)
+
+// listBuilds returns the build numbers available upstream for platform in ascending order.
+// The snapshots bucket stores builds as "directories" below the platform, which are enumerated page by page via the JSON API.
+func listBuilds(client *http.Client, up upstream, platform string) ([]string, error) {
+	var builds []string
+	pageToken := ""
+	for {
+		query := url.Values{}
+		query.Set("prefix", platform+"/")
+		query.Set("delimiter", "/")
+		query.Set("fields", "prefixes,nextPageToken")
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		resp, err := client.Get(up.listURL() + "?" + query.Encode())
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Prefixes      []string `json:"prefixes"`
+			NextPageToken string   `json:"nextPageToken"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("listing builds responded with HTTP status %s", resp.Status)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, prefix := range page.Prefixes {
+			build := strings.TrimSuffix(strings.TrimPrefix(prefix, platform+"/"), "/")
+			if _, err := strconv.ParseUint(build, 10, 64); err == nil {
+				builds = append(builds, build)
+			}
+		}
+
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+
+	sort.Slice(builds, func(i, j int) bool {
+		a, _ := strconv.ParseUint(builds[i], 10, 64)
+		b, _ := strconv.ParseUint(builds[j], 10, 64)
+		return a < b
+	})
+	return builds, nil
+}
+
+// printContents prints the entries of an archive, or a JSON array of them with -json.
+// Entries which would not be extracted, like the top directory, are marked.
+func printContents(contents []downloadextract.ArchiveEntry) {
+	if jsonOutput {
+		type listedEntry struct {
+			Name string `json:"name"`
+			Path string `json:"path"`
+			Size int64  `json:"size"`
+			Mode string `json:"mode"`
+		}
+		listed := make([]listedEntry, 0, len(contents))
+		for _, e := range contents {
+			listed = append(listed, listedEntry{Name: e.Name, Path: e.Path, Size: e.Size, Mode: e.Mode.String()})
+		}
+		json.NewEncoder(os.Stdout).Encode(listed)
+		return
+	}
+	for _, e := range contents {
+		note := ""
+		if e.Path == "" {
+			note = " (not extracted)"
+		}
+		fmt.Printf("%s %12d %s%s\n", e.Mode, e.Size, e.Name, note)
+	}
+}
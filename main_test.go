@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fried-ice/chromiumup/snapshots"
+)
+
+func TestResolveArchive(t *testing.T) {
+	// Only the historical name of the archive exists
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/Win_x64/123/chrome-win32.zip" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"md5Hash": "Ojk9c3dhfxgoKVVHYwFbHQ=="}`))
+	}))
+	defer srv.Close()
+	up := upstream{base: srv.URL + "/", apiBase: srv.URL + "/api/", sep: "/"}
+
+	file, md5Sum, err := resolveArchive(srv.Client(), up, "Win_x64", "123", archiveFiles("Win_x64", "chrome-win.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file != "chrome-win32.zip" || md5Sum != "3a393d7377617f182829554763015b1d" {
+		t.Errorf("resolved %s with md5 %s, want chrome-win32.zip with 3a393d7377617f182829554763015b1d", file, md5Sum)
+	}
+
+	file, _, err = resolveArchive(srv.Client(), up, "Win_x64", "124", archiveFiles("Win_x64", "chrome-win.zip"))
+	if !errors.Is(err, snapshots.ErrRevisionNotFound) || file != "chrome-win.zip" {
+		t.Errorf("resolved %s with error %v for a missing build, want chrome-win.zip and ErrRevisionNotFound", file, err)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int64
+		ok   bool
+	}{
+		{s: "512", want: 512, ok: true},
+		{s: "512K", want: 512 * 1024, ok: true},
+		{s: "2m", want: 2 * 1024 * 1024, ok: true},
+		{s: "1G", want: 1024 * 1024 * 1024, ok: true},
+		{s: "9223372036854775807", want: math.MaxInt64, ok: true},
+		{s: "8589934591G", want: 8589934591 * 1024 * 1024 * 1024, ok: true},
+		{s: "8589934592G"},
+		{s: "9007199254740992M"},
+		{s: "-1K"},
+		{s: "K"},
+	}
+	for _, test := range tests {
+		got, err := parseByteSize(test.s)
+		if ok := err == nil; ok != test.ok || got != test.want {
+			t.Errorf("parseByteSize(%s) = %v, %v", test.s, got, err)
+		}
+	}
+}
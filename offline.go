@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// checkArchiveFile returns an error if there is no readable file at path.
+// Whether it is an archive is checked by the extraction before anything is written.
+func checkArchiveFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return errors.New("path is a directory")
+	}
+	return nil
+}
+
+// fileURL returns the file URL of path, which is recorded as source of builds installed with -from-file.
+func fileURL(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String(), nil
+}
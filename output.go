@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+var (
+	// jsonOutput is set by the -json flag.
+	jsonOutput bool
+	// stdout receives all human readable output, which is discarded with -json.
+	stdout io.Writer = os.Stdout
+)
+
+// summary is printed as JSON object with -json after a successful run.
+type summary struct {
+	Revision   string `json:"revision"`
+	Platform   string `json:"platform"`
+	TargetPath string `json:"targetPath"`
+	// Copies are the further target paths, which received copies of the build at TargetPath.
+	Copies     []string `json:"copies,omitempty"`
+	UpToDate   bool     `json:"upToDate,omitempty"`
+	Files      int      `json:"files"`
+	TotalBytes int64    `json:"totalBytes"`
+	Duration   float64  `json:"durationSeconds"`
+	// NetworkWait and DiskWait tell whether the run was limited by the network or the disk, see downloadextract.Result.
+	NetworkWait float64 `json:"networkWaitSeconds"`
+	DiskWait    float64 `json:"diskWaitSeconds"`
+}
+
+// printSummary prints s as JSON object, if -json is set.
+func printSummary(s summary) {
+	if jsonOutput {
+		json.NewEncoder(os.Stdout).Encode(s)
+	}
+}
+
+// printBuilds prints the build numbers found by -list for platform, one per line or, with -json, as JSON object.
+func printBuilds(platform string, builds []string) {
+	if !jsonOutput {
+		for _, b := range builds {
+			fmt.Println(b)
+		}
+		return
+	}
+	if builds == nil {
+		builds = []string{}
+	}
+	json.NewEncoder(os.Stdout).Encode(struct {
+		Platform string   `json:"platform"`
+		Builds   []string `json:"builds"`
+	}{platform, builds})
+}
+
+// fail reports an error and exits with code.
+// With -json, the error is printed as JSON object to stdout, otherwise as message to stderr.
+func fail(code int, format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	if jsonOutput {
+		json.NewEncoder(os.Stdout).Encode(struct {
+			Error string `json:"error"`
+		}{msg})
+	} else {
+		fmt.Fprintln(os.Stderr, msg)
+	}
+	os.Exit(code)
+}
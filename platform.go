@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/fried-ice/chromiumup/snapshots"
+)
+
+// platforms lists the upstream platform directories of snapshot builds, with the system their builds run on and their archive file name.
+// executable is the slash separated path of the browser executable within the extracted archive, without its top directory.
+// alternates are names the archive file had at other times, which are tried if an archive of the usual name does not exist.
+var platforms = []struct {
+	goos       string
+	platform   string
+	file       string
+	executable string
+	alternates []string
+}{
+	{"linux", "Linux_x64", "chrome-linux.zip", "chrome", nil},
+	{"linux", "Linux", "chrome-linux.zip", "chrome", nil},
+	{"linux", "Linux_Arm", "chrome-linux.zip", "chrome", nil},
+	{"windows", "Win_x64", "chrome-win.zip", "chrome.exe", []string{"chrome-win32.zip"}},
+	{"windows", "Win", "chrome-win.zip", "chrome.exe", []string{"chrome-win32.zip"}},
+	// Intel and Apple Silicon builds share the archive name, but live in different platform directories
+	{"darwin", "Mac", "chrome-mac.zip", "Chromium.app/Contents/MacOS/Chromium", nil},
+	{"darwin", "Mac_Arm", "chrome-mac.zip", "Chromium.app/Contents/MacOS/Chromium", nil},
+}
+
+// platformStrings returns the upstream platform directory and archive file name for the running system.
+func platformStrings() (platform string, file string, err error) {
+	return platformStringsFor(runtime.GOOS, runtime.GOARCH)
+}
+
+// platformStringsFor returns the upstream platform directory and archive file name for the given GOOS and GOARCH.
+// An error wrapping snapshots.ErrUnsupportedPlatform is returned if there are no snapshot builds for this combination.
+func platformStringsFor(goos string, goarch string) (platform string, file string, err error) {
+	platform, err = snapshots.Platform(goos, goarch)
+	if err != nil {
+		return "", "", err
+	}
+	return platformStringsByName(platform)
+}
+
+// platformStringsByName returns the upstream platform directory and archive file name for the platform directory name.
+// An error is returned if name is not a known platform directory.
+func platformStringsByName(name string) (platform string, file string, err error) {
+	for _, p := range platforms {
+		if p.platform == name {
+			return p.platform, p.file, nil
+		}
+	}
+	return "", "", fmt.Errorf("unknown platform \"%s\"", name)
+}
+
+// platformNames returns the names of all known platform directories.
+func platformNames() []string {
+	names := make([]string, 0, len(platforms))
+	for _, p := range platforms {
+		names = append(names, p.platform)
+	}
+	return names
+}
+
+// verifyExecutable returns an error if the installation at path of the archive file of platform lacks the browser executable.
+// Archive files other than the ones of the platform have an unknown layout and are not verified.
+// Unless Windows is involved, which lacks Unix permissions, the executable must have an executable bit set.
+func verifyExecutable(path string, platform string, file string) error {
+	for _, p := range platforms {
+		if p.platform != platform || !hasArchiveFile(p.file, p.alternates, file) {
+			continue
+		}
+		executable := filepath.FromSlash(p.executable)
+		fInfo, err := os.Stat(filepath.Join(path, executable))
+		if err != nil {
+			return fmt.Errorf("browser executable \"%s\" is missing", executable)
+		}
+		if !fInfo.Mode().IsRegular() {
+			return fmt.Errorf("browser executable \"%s\" is no regular file", executable)
+		}
+		if runtime.GOOS != "windows" && p.goos != "windows" && fInfo.Mode().Perm()&0111 == 0 {
+			return fmt.Errorf("browser executable \"%s\" is not executable", executable)
+		}
+		return nil
+	}
+	return nil
+}
+
+// archiveFiles returns the archive file name of platform followed by its alternate names, or just file if it is no archive file name of platform.
+func archiveFiles(platform string, file string) []string {
+	for _, p := range platforms {
+		if p.platform == platform && p.file == file {
+			return append([]string{p.file}, p.alternates...)
+		}
+	}
+	return []string{file}
+}
+
+// hasArchiveFile reports whether file is the archive file name or one of the alternate names.
+func hasArchiveFile(name string, alternates []string, file string) bool {
+	if name == file {
+		return true
+	}
+	for _, a := range alternates {
+		if a == file {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/fried-ice/chromiumup/snapshots"
+)
+
+func TestPlatformStringsFor(t *testing.T) {
+	tests := []struct {
+		goos     string
+		goarch   string
+		platform string
+		file     string
+	}{
+		{goos: "darwin", goarch: "arm64", platform: "Mac_Arm", file: "chrome-mac.zip"},
+		{goos: "darwin", goarch: "amd64", platform: "Mac", file: "chrome-mac.zip"},
+		{goos: "linux", goarch: "arm64", platform: "Linux_Arm", file: "chrome-linux.zip"},
+		{goos: "linux", goarch: "amd64", platform: "Linux_x64", file: "chrome-linux.zip"},
+		{goos: "linux", goarch: "386", platform: "Linux", file: "chrome-linux.zip"},
+		{goos: "windows", goarch: "amd64", platform: "Win_x64", file: "chrome-win.zip"},
+		{goos: "windows", goarch: "386", platform: "Win", file: "chrome-win.zip"},
+	}
+	for _, test := range tests {
+		platform, file, err := platformStringsFor(test.goos, test.goarch)
+		if err != nil || platform != test.platform || file != test.file {
+			t.Errorf("platformStringsFor(%s, %s) = %s, %s, %v, want %s, %s", test.goos, test.goarch, platform, file, err, test.platform, test.file)
+		}
+	}
+
+	_, _, err := platformStringsFor("linux", "riscv64")
+	if !errors.Is(err, snapshots.ErrUnsupportedPlatform) {
+		t.Errorf("platformStringsFor(linux, riscv64) returned %v, want ErrUnsupportedPlatform", err)
+	}
+}
+
+func TestArchiveFiles(t *testing.T) {
+	tests := []struct {
+		platform string
+		file     string
+		want     []string
+	}{
+		{platform: "Win_x64", file: "chrome-win.zip", want: []string{"chrome-win.zip", "chrome-win32.zip"}},
+		{platform: "Win", file: "chrome-win.zip", want: []string{"chrome-win.zip", "chrome-win32.zip"}},
+		{platform: "Linux_x64", file: "chrome-linux.zip", want: []string{"chrome-linux.zip"}},
+		// Names other than the archive file name of the platform are used as they are
+		{platform: "Win_x64", file: "chrome-win32.zip", want: []string{"chrome-win32.zip"}},
+		{platform: "Linux_x64", file: "content-shell.zip", want: []string{"content-shell.zip"}},
+	}
+	for _, test := range tests {
+		if got := archiveFiles(test.platform, test.file); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("archiveFiles(%s, %s) = %v, want %v", test.platform, test.file, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fried-ice/chromiumup/internal/display"
+)
+
+const (
+	// progressBarWidth is the number of characters of the bar itself.
+	progressBarWidth = 30
+	// progressBarInterval is the minimum time between redraws of the bar.
+	progressBarInterval = 200 * time.Millisecond
+)
+
+// progressBar renders the download progress on a single terminal line, with percentage, speed and remaining time.
+type progressBar struct {
+	w        io.Writer
+	start    time.Time
+	mu       sync.Mutex
+	last     time.Time
+	drawn    bool
+	finished bool
+}
+
+func newProgressBar(w io.Writer) *progressBar {
+	return &progressBar{w: w, start: time.Now()}
+}
+
+// update is a downloadextract.ProgressFunc which redraws the bar, unless it was drawn too recently.
+func (p *progressBar) update(bytesDownloaded, totalBytes int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if p.finished || now.Sub(p.last) < progressBarInterval && bytesDownloaded != totalBytes {
+		return
+	}
+	p.last = now
+	p.drawn = true
+
+	speed := float64(bytesDownloaded) / now.Sub(p.start).Seconds()
+	if totalBytes <= 0 {
+		fmt.Fprintf(p.w, "\r%s  %s/s\x1b[K", display.FormatBytes(uint64(bytesDownloaded)), display.FormatBytes(uint64(speed)))
+		return
+	}
+	done := int(float64(progressBarWidth) * float64(bytesDownloaded) / float64(totalBytes))
+	if done > progressBarWidth {
+		done = progressBarWidth
+	}
+	eta := "--"
+	if speed > 0 {
+		eta = (time.Duration(float64(totalBytes-bytesDownloaded)/speed) * time.Second).Round(time.Second).String()
+	}
+	fmt.Fprintf(p.w, "\r[%s%s] %3d%%  %s / %s  %s/s  ETA %s\x1b[K",
+		strings.Repeat("=", done), strings.Repeat(" ", progressBarWidth-done),
+		bytesDownloaded*100/totalBytes, display.FormatBytes(uint64(bytesDownloaded)), display.FormatBytes(uint64(totalBytes)), display.FormatBytes(uint64(speed)), eta)
+	if bytesDownloaded >= totalBytes {
+		// Output following the download must not end up on the line of the bar
+		fmt.Fprintln(p.w)
+		p.drawn = false
+		p.finished = true
+	}
+}
+
+// finish ends the line of the bar, so subsequent output starts on a new line.
+func (p *progressBar) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.drawn {
+		fmt.Fprintln(p.w)
+		p.drawn = false
+	}
+}
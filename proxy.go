@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// proxyErrorTransport explains failures of base to connect to the proxy, which otherwise read like failures to reach the server.
+type proxyErrorTransport struct {
+	base *http.Transport
+}
+
+func (t *proxyErrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	var opErr *net.OpError
+	if err == nil || !errors.As(err, &opErr) || opErr.Op != "proxyconnect" || t.base.Proxy == nil {
+		return resp, err
+	}
+	proxyURL, proxyErr := t.base.Proxy(req)
+	if proxyErr != nil || proxyURL == nil {
+		return resp, err
+	}
+	// The URL of the proxy may contain credentials, which must not end up in the output
+	return nil, fmt.Errorf("failed to connect via proxy %s://%s: %w", proxyURL.Scheme, proxyURL.Host, opErr.Err)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestProxyErrorTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	// Nothing listens at the port of the proxy anymore
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyAddr := l.Addr().String()
+	l.Close()
+
+	base := &http.Transport{Proxy: http.ProxyURL(&url.URL{Scheme: "http", User: url.UserPassword("user", "secret"), Host: proxyAddr})}
+	client := &http.Client{Transport: &proxyErrorTransport{base: base}}
+	_, err = client.Get(srv.URL)
+	if err == nil {
+		t.Fatal("request succeeded without a proxy")
+	}
+	if want := "failed to connect via proxy http://" + proxyAddr + ": "; !strings.Contains(err.Error(), want) {
+		t.Errorf("request failed with %q, want it to contain %q", err, want)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("request failed with %q, which contains the credentials of the proxy", err)
+	}
+}
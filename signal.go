@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// interruptExitCode is the exit code of chromiumup if it is interrupted by SIGINT or SIGTERM.
+const interruptExitCode = 1
+
+// handleSignals cleans up inst according to its phase when SIGINT or SIGTERM is received and exits with exitCode afterwards.
+// The returned cleanup function does the same cleanup without exiting and stops the handling of signals. It only takes effect once.
+// As the installation takes no further step after the cleanup, it is only meant to be called when the installation is abandoned or finished.
+// track adds further installations, like those of copies of the build, which are cleaned up along with inst.
+func handleSignals(inst *installation, exitCode int) (cleanup func(), track func(*installation)) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	var mu sync.Mutex
+	insts := []*installation{inst}
+	track = func(i *installation) {
+		mu.Lock()
+		defer mu.Unlock()
+		insts = append(insts, i)
+	}
+	// done ends the goroutine waiting for signals, which receives none after signal.Stop
+	done := make(chan struct{})
+	var once sync.Once
+	cleanup = func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+			mu.Lock()
+			defer mu.Unlock()
+			for _, i := range insts {
+				i.interrupt()
+			}
+		})
+	}
+	go func() {
+		select {
+		case sig := <-signals:
+			println("Received " + sig.String() + " signal")
+			cleanup()
+			os.Exit(exitCode)
+		case <-done:
+		}
+	}()
+	return cleanup, track
+}
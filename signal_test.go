@@ -0,0 +1,24 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestHandleSignalsCleanup(t *testing.T) {
+	inst := newTestInstallation(t)
+	copyInst := newInstallation(filepath.Join(t.TempDir(), "copy"+tmpExt), filepath.Join(t.TempDir(), "copy"))
+	writeBuild(t, copyInst.tmpPath, "new")
+
+	cleanup, track := handleSignals(inst, interruptExitCode)
+	track(copyInst)
+	cleanup()
+	// Further calls have no effect
+	cleanup()
+
+	assertNotExist(t, inst.tmpPath)
+	assertNotExist(t, copyInst.tmpPath)
+	if buildAt(inst.targetPath) != "old" {
+		t.Errorf("found %q at the target path, want the old build", buildAt(inst.targetPath))
+	}
+}
@@ -0,0 +1,256 @@
+// Package snapshots resolves Chromium snapshot builds, as stored in the chromium-browser-snapshots Google Storage bucket or a mirror of it.
+package snapshots
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fried-ice/chromiumup/downloadextract"
+	"github.com/fried-ice/chromiumup/internal/backoff"
+)
+
+const (
+	// DefaultBaseURL is the URL objects of the snapshots bucket are downloaded from, followed by their path.
+	DefaultBaseURL = "https://www.googleapis.com/download/storage/v1/b/chromium-browser-snapshots/o/"
+	// DefaultSeparator separates the elements of object paths in URLs of the bucket.
+	DefaultSeparator = "%2F"
+	// DefaultLatestObject is the name of the object within a platform directory, which contains the latest build number.
+	DefaultLatestObject = "LAST_CHANGE"
+	// DefaultParams is appended to download URLs of the bucket.
+	DefaultParams = "?alt=media"
+	// DefaultRetries is the number of times a failed request is retried by a Client created by NewClient.
+	DefaultRetries = 3
+	// DefaultRetryDelay is the delay before the first retry of a Client created by NewClient.
+	DefaultRetryDelay = time.Second
+)
+
+var (
+	// ErrRevisionNotFound is wrapped by the error returned if a build, or its archive, does not exist.
+	ErrRevisionNotFound = errors.New("revision not found")
+	// ErrUnsupportedPlatform is wrapped by the error of Platform if there are no builds for a combination of GOOS and GOARCH.
+	// LatestRevision does not wrap it, as a missing latest build number is just as likely caused by a wrong base URL or LatestObject.
+	ErrUnsupportedPlatform = errors.New("unsupported platform")
+)
+
+// platforms maps the GOOS and GOARCH combinations with snapshot builds to their platform directory.
+var platforms = map[[2]string]string{
+	{"linux", "amd64"}:   "Linux_x64",
+	{"linux", "386"}:     "Linux",
+	{"linux", "arm64"}:   "Linux_Arm",
+	{"windows", "amd64"}: "Win_x64",
+	{"windows", "386"}:   "Win",
+	{"darwin", "amd64"}:  "Mac",
+	{"darwin", "arm64"}:  "Mac_Arm",
+}
+
+// Platform returns the platform directory of the builds for goos and goarch, like "Linux_x64" for linux and amd64.
+// An error wrapping ErrUnsupportedPlatform is returned if there are no builds for them.
+func Platform(goos string, goarch string) (string, error) {
+	platform, ok := platforms[[2]string{goos, goarch}]
+	if !ok {
+		return "", fmt.Errorf("%w: there are no Chromium snapshot builds for %s/%s", ErrUnsupportedPlatform, goos, goarch)
+	}
+	return platform, nil
+}
+
+// Client resolves snapshot builds. Its fields describe the layout of the bucket, so mirrors with a different one are supported.
+type Client struct {
+	// HTTPClient sends the requests.
+	HTTPClient *http.Client
+	// Separator separates the elements of object paths.
+	Separator string
+	// LatestObject is the name of the object within a platform directory which contains the latest build number.
+	// Slashes separate further path elements.
+	LatestObject string
+	// Params is appended to download URLs.
+	Params string
+	// Retries is the number of times a request is retried on network errors and 5xx responses.
+	// The delay before the n-th retry is RetryDelay * 2^(n-1), as for downloadextract.DownloadExtractor.SetRetries.
+	Retries    int
+	RetryDelay time.Duration
+}
+
+// NewClient creates a Client for the layout of the snapshots bucket, which sends requests with httpClient.
+func NewClient(httpClient *http.Client) *Client {
+	return &Client{
+		HTTPClient:   httpClient,
+		Separator:    DefaultSeparator,
+		LatestObject: DefaultLatestObject,
+		Params:       DefaultParams,
+		Retries:      DefaultRetries,
+		RetryDelay:   DefaultRetryDelay,
+	}
+}
+
+// LatestRevision returns the number of the latest build of platform, like "Linux_x64", from the bucket at baseURL, like DefaultBaseURL.
+// It uses the default http client of downloadextract.
+func LatestRevision(ctx context.Context, baseURL string, platform string) (string, error) {
+	return NewClient(downloadextract.NewHTTPClient()).LatestRevision(ctx, baseURL, platform)
+}
+
+// LatestURL returns the URL of the object containing the latest build number of platform in the bucket at baseURL.
+func (c *Client) LatestURL(baseURL string, platform string) (string, error) {
+	elements := append([]string{platform}, strings.Split(c.LatestObject, "/")...)
+	return ObjectURL(baseURL, c.Separator, c.Params, elements...)
+}
+
+// maxRevisionSnippet is the number of bytes of an invalid latest build number included in the error message.
+const maxRevisionSnippet = 64
+
+// LatestRevision returns the number of the latest build of platform from the bucket at baseURL.
+// An error is returned if the object does not contain a decimal number.
+func (c *Client) LatestRevision(ctx context.Context, baseURL string, platform string) (string, error) {
+	latestURL, err := c.LatestURL(baseURL, platform)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.get(ctx, latestURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("latest build number of platform \"%s\" not found at \"%s\"", platform, latestURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("request responded with HTTP status %s", resp.Status)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	revision := strings.TrimSpace(string(b))
+	if _, err := strconv.ParseUint(revision, 10, 64); err != nil {
+		// A mirror may answer with an error page instead, whose beginning tells what went wrong
+		if len(revision) > maxRevisionSnippet {
+			revision = revision[:maxRevisionSnippet] + "..."
+		}
+		return "", fmt.Errorf("latest build number of platform \"%s\" at \"%s\" is not a decimal number: %q", platform, latestURL, revision)
+	}
+	return revision, nil
+}
+
+// ArchiveMD5 returns the hex encoded md5 hash of the archive file of build revision of platform, as stated in its metadata retrieved from apiBaseURL.
+// apiBaseURL is the URL of the JSON API of the bucket, like "https://www.googleapis.com/storage/v1/b/chromium-browser-snapshots/o/".
+// An error wrapping ErrRevisionNotFound is returned if the archive does not exist.
+func (c *Client) ArchiveMD5(ctx context.Context, apiBaseURL string, platform string, revision string, file string) (string, error) {
+	metadataURL, err := ObjectURL(apiBaseURL, c.Separator, "", platform, revision, file)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.get(ctx, metadataURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%w: archive \"%s\" of build %s does not exist for platform %s", ErrRevisionNotFound, file, revision, platform)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata request responded with HTTP status %s", resp.Status)
+	}
+
+	var metadata struct {
+		MD5Hash string `json:"md5Hash"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&metadata)
+	if err != nil {
+		return "", err
+	}
+	if metadata.MD5Hash == "" {
+		return "", errors.New("metadata contains no md5Hash")
+	}
+	sum, err := base64.StdEncoding.DecodeString(metadata.MD5Hash)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+// errServerStatus is wrapped by the error of a request answered with a server error, which is worth retrying.
+var errServerStatus = errors.New("request responded with HTTP status")
+
+// transferError is the error of a request which was sent, but received no response, like when the connection was refused or dropped.
+type transferError struct {
+	err error
+}
+
+func (e *transferError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transferError) Unwrap() error {
+	return e.err
+}
+
+// get sends a GET request to url and retries it according to the Retries and RetryDelay fields.
+// The returned response is successful in terms of not being a server error.
+func (c *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	var resp *http.Response
+	err := backoff.Retry(ctx, c.Retries, c.RetryDelay, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err = c.HTTPClient.Do(req)
+		if err != nil {
+			return &transferError{err: err}
+		}
+		if resp.StatusCode >= http.StatusInternalServerError {
+			resp.Body.Close()
+			return fmt.Errorf("%w %s", errServerStatus, resp.Status)
+		}
+		return nil
+	}, retryable, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// retryable reports whether a request failing with err may succeed when sent again.
+// As for downloadextract, this is the case for transfer errors and server errors.
+func retryable(err error) bool {
+	var transferErr *transferError
+	return errors.As(err, &transferErr) || errors.Is(err, errServerStatus)
+}
+
+// ObjectURL returns the URL of the object whose path consists of elements below baseURL.
+// Every element is escaped, so a slash within it cannot be mistaken for one separating elements, and the elements are joined with sep.
+// params is appended to the URL, with a leading "?" starting its query.
+func ObjectURL(baseURL string, sep string, params string, elements ...string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("base URL \"%s\" is not absolute", baseURL)
+	}
+	escaped := make([]string, len(elements))
+	for i, e := range elements {
+		escaped[i] = url.PathEscape(e)
+	}
+	rawPath := u.EscapedPath() + strings.Join(escaped, sep)
+	if strings.HasPrefix(params, "?") {
+		u.RawQuery = params[1:]
+	} else {
+		rawPath += params
+	}
+	u.Path, err = url.PathUnescape(rawPath)
+	if err != nil {
+		return "", err
+	}
+	u.RawPath = rawPath
+	return u.String(), nil
+}
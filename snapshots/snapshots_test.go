@@ -0,0 +1,165 @@
+package snapshots
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a Client for a mirror which joins path elements with slashes and needs no parameters.
+func newTestClient() *Client {
+	return &Client{HTTPClient: http.DefaultClient, Separator: "/", LatestObject: DefaultLatestObject}
+}
+
+func TestLatestRevisionCustomObject(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/Linux_x64/latest/REVISION" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("1234567\n"))
+	}))
+	defer srv.Close()
+
+	c := newTestClient()
+	c.LatestObject = "latest/REVISION"
+	revision, err := c.LatestRevision(context.Background(), srv.URL+"/", "Linux_x64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision != "1234567" {
+		t.Errorf("LatestRevision = %q, want 1234567", revision)
+	}
+}
+
+func TestObjectURL(t *testing.T) {
+	tests := []struct {
+		baseURL  string
+		sep      string
+		params   string
+		elements []string
+		want     string
+	}{
+		{
+			baseURL: DefaultBaseURL, sep: DefaultSeparator, params: DefaultParams,
+			elements: []string{"Linux_x64", "1234567", "chrome-linux.zip"},
+			want:     "https://www.googleapis.com/download/storage/v1/b/chromium-browser-snapshots/o/Linux_x64%2F1234567%2Fchrome-linux.zip?alt=media",
+		},
+		{
+			baseURL: "https://mirror.example.com/snapshots/", sep: "/",
+			elements: []string{"Linux_x64", "1234567", "chrome-linux.zip"},
+			want:     "https://mirror.example.com/snapshots/Linux_x64/1234567/chrome-linux.zip",
+		},
+		{
+			baseURL: "https://mirror.example.com/", sep: "/",
+			elements: []string{"Linux x64", "12/34?5#6", "chrome%linux.zip"},
+			want:     "https://mirror.example.com/Linux%20x64/12%2F34%3F5%236/chrome%25linux.zip",
+		},
+		{
+			baseURL: "https://mirror.example.com/o/", sep: DefaultSeparator, params: "?alt=media&token=a%2Fb",
+			elements: []string{"Mac", "1234567"},
+			want:     "https://mirror.example.com/o/Mac%2F1234567?alt=media&token=a%2Fb",
+		},
+		{
+			baseURL: "https://mirror.example.com/", sep: "/", params: ".zip",
+			elements: []string{"Mac", "1234567"},
+			want:     "https://mirror.example.com/Mac/1234567.zip",
+		},
+	}
+	for _, test := range tests {
+		got, err := ObjectURL(test.baseURL, test.sep, test.params, test.elements...)
+		if err != nil || got != test.want {
+			t.Errorf("ObjectURL(%s, %s, %s, %v) = %s, %v, want %s", test.baseURL, test.sep, test.params, test.elements, got, err, test.want)
+		}
+	}
+
+	_, err := ObjectURL("mirror.example.com/snapshots/", "/", "", "Mac")
+	if err == nil {
+		t.Error("ObjectURL accepted a relative base URL")
+	}
+}
+
+func TestLatestRevisionRetries(t *testing.T) {
+	// The first request is dropped without a response, the second one fails with a server error
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&requests, 1) {
+		case 1:
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte("1234567"))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		retries int
+		ok      bool
+	}{
+		{retries: 1, ok: false},
+		{retries: 2, ok: true},
+	}
+	for _, test := range tests {
+		atomic.StoreInt32(&requests, 0)
+		c := newTestClient()
+		c.Retries, c.RetryDelay = test.retries, time.Millisecond
+		revision, err := c.LatestRevision(context.Background(), srv.URL+"/", "Linux_x64")
+		if ok := err == nil && revision == "1234567"; ok != test.ok {
+			t.Errorf("LatestRevision with %v retries returned %q, %v", test.retries, revision, err)
+		}
+	}
+}
+
+func TestLatestRevisionNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	c := newTestClient()
+	_, err := c.LatestRevision(context.Background(), srv.URL+"/", "Linux_x64")
+	if err == nil {
+		t.Fatal("LatestRevision succeeded without a latest build number")
+	}
+	if errors.Is(err, ErrUnsupportedPlatform) {
+		t.Errorf("LatestRevision returned %v, which misreports the platform as unsupported", err)
+	}
+	if want := srv.URL + "/Linux_x64/LAST_CHANGE"; !strings.Contains(err.Error(), want) {
+		t.Errorf("LatestRevision returned %q, which does not name %s", err, want)
+	}
+}
+
+func TestLatestRevisionNotDecimal(t *testing.T) {
+	bodies := []string{"", "<html><body>Access denied</body></html>", "12a4", "-5"}
+	for _, body := range bodies {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}))
+		revision, err := newTestClient().LatestRevision(context.Background(), srv.URL+"/", "Linux_x64")
+		srv.Close()
+		if err == nil {
+			t.Errorf("LatestRevision returned %q for the body %q, want an error", revision, body)
+		} else if !strings.Contains(err.Error(), "not a decimal number") {
+			t.Errorf("LatestRevision returned %q for the body %q, which does not describe the problem", err, body)
+		}
+	}
+}
+
+func TestPlatform(t *testing.T) {
+	platform, err := Platform("darwin", "arm64")
+	if err != nil || platform != "Mac_Arm" {
+		t.Errorf("Platform(darwin, arm64) = %s, %v, want Mac_Arm", platform, err)
+	}
+	_, err = Platform("linux", "riscv64")
+	if !errors.Is(err, ErrUnsupportedPlatform) {
+		t.Errorf("Platform(linux, riscv64) returned %v, want ErrUnsupportedPlatform", err)
+	}
+}
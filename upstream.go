@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/fried-ice/chromiumup/snapshots"
+)
+
+// upstream describes where and how snapshot builds are stored.
+// Besides the Google Storage bucket of the Chromium project, this may be a mirror with a different layout.
+type upstream struct {
+	// base is prepended to object paths to download them.
+	base string
+	// apiBase is prepended to object paths to retrieve their metadata, and is used to list objects.
+	apiBase string
+	// sep separates the elements of object paths.
+	sep string
+	// lastChange is the name of the object within a platform directory, which contains the latest build number.
+	// It may consist of several slash separated path elements.
+	lastChange string
+	// params is appended to download URLs.
+	params string
+}
+
+// defaultUpstream is the Google Storage bucket of Chromium snapshot builds.
+var defaultUpstream = upstream{
+	base:       snapshots.DefaultBaseURL,
+	apiBase:    "https://www.googleapis.com/storage/v1/b/chromium-browser-snapshots/o/",
+	sep:        snapshots.DefaultSeparator,
+	lastChange: snapshots.DefaultLatestObject,
+	params:     snapshots.DefaultParams,
+}
+
+// downloadURL returns the URL to download the object whose path consists of elements.
+func (u upstream) downloadURL(elements ...string) (string, error) {
+	return snapshots.ObjectURL(u.base, u.sep, u.params, elements...)
+}
+
+// snapshots returns a client resolving builds from the upstream, which sends requests with client and retries them up to retries times.
+func (u upstream) snapshots(client *http.Client, retries int) *snapshots.Client {
+	return &snapshots.Client{HTTPClient: client, Separator: u.sep, LatestObject: u.lastChange, Params: u.params, Retries: retries, RetryDelay: retryDelay}
+}
+
+// listURL returns the URL to list objects, without any query parameters.
+func (u upstream) listURL() string {
+	return strings.TrimSuffix(u.apiBase, "/")
+}
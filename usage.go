@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// usage prints how to invoke the tool, including the detected platform and all flags.
+func usage() {
+	out := flag.CommandLine.Output()
+	detected := "unsupported"
+	if platform, file, err := platformStrings(); err == nil {
+		detected = platform + " (" + file + ")"
+	}
+	fmt.Fprintf(out, "Usage: %s [flags] [target-path...]\n\n", os.Args[0])
+	fmt.Fprintf(out, "Downloads a Chromium snapshot build, by default the latest one, and installs it at target-path, which defaults to \"chromium\".\n")
+	fmt.Fprintf(out, "Further target paths receive copies of the build installed at the first one, so it is only downloaded once.\n")
+	fmt.Fprintf(out, "The build is chosen for the platform detected from %s/%s, which is %s, unless -platform is given.\n\n", runtime.GOOS, runtime.GOARCH, detected)
+	fmt.Fprintf(out, "Flags:\n")
+	flag.PrintDefaults()
+}
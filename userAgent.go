@@ -0,0 +1,19 @@
+package main
+
+import "net/http"
+
+// defaultUserAgent identifies requests of the tool and its version in the logs of mirrors.
+var defaultUserAgent = "chromiumup/" + version
+
+// userAgentTransport sets the User-Agent header of all requests before handing them to base.
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request it was given
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.base.RoundTrip(req)
+}
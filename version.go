@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+)
+
+// The version of the tool, set at build time like -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.date=$(date -u +%FT%TZ)".
+var (
+	version = "dev"
+	commit  = "unknown"
+	date    = "unknown"
+)
+
+// metadataFile is the name of the file within an installation which describes the installed build.
+const metadataFile = ".chromiumup.json"
+
+// installMetadata describes an installed build.
+type installMetadata struct {
+	Revision  string    `json:"revision"`
+	Platform  string    `json:"platform"`
+	Installed time.Time `json:"installed"`
+	SourceURL string    `json:"sourceUrl"`
+	// ETag and LastModified identify the version of the archive at SourceURL, to only download it again if it changed
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// readMetadata returns the metadata of the installation at path.
+func readMetadata(path string) (*installMetadata, error) {
+	b, err := ioutil.ReadFile(filepath.Join(path, metadataFile))
+	if err != nil {
+		return nil, err
+	}
+	var m installMetadata
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// writeMetadata stores m as metadata of the installation at path.
+func writeMetadata(path string, m *installMetadata) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(path, metadataFile), append(b, '\n'), 0644)
+}